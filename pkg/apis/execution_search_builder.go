@@ -0,0 +1,189 @@
+package apis
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// SearchBuilder constructs TestExecutionSearch criteria and validates them before use.
+// Methods can be chained, problems are collected and reported by Build.
+type SearchBuilder struct {
+	search TestExecutionSearch
+	errs   []string
+}
+
+// NewSearch creates an empty SearchBuilder
+func NewSearch() *SearchBuilder {
+	return &SearchBuilder{}
+}
+
+// ForTestUID restricts the search to executions of the test with the given UID
+func (b *SearchBuilder) ForTestUID(uid string) *SearchBuilder {
+	if uid == "" {
+		b.errorf("Test UID must not be empty")
+	}
+	b.search.TestUID = uid
+	return b
+}
+
+// ForTestName restricts the search to executions of tests with the given name
+func (b *SearchBuilder) ForTestName(name string) *SearchBuilder {
+	if name == "" {
+		b.errorf("Test name must not be empty")
+	}
+	b.search.TestName = name
+	return b
+}
+
+// ForIDs restricts the search to executions with the given IDs
+func (b *SearchBuilder) ForIDs(ids ...int64) *SearchBuilder {
+	if len(ids) == 0 {
+		b.errorf("At least one execution ID is required")
+	}
+	all := append([]int64(nil), ids...)
+	if b.search.IDS != nil {
+		all = append(*b.search.IDS, all...)
+	}
+	b.search.IDS = &all
+	return b
+}
+
+// WithTags restricts the search to executions having all of the given tags
+func (b *SearchBuilder) WithTags(tags ...string) *SearchBuilder {
+	for _, tag := range tags {
+		b.addTag(tag, "")
+	}
+	return b
+}
+
+// WithoutTags restricts the search to executions having none of the given tags
+func (b *SearchBuilder) WithoutTags(tags ...string) *SearchBuilder {
+	for _, tag := range tags {
+		b.addTag(tag, "-")
+	}
+	return b
+}
+
+func (b *SearchBuilder) addTag(tag, prefix string) {
+	if tag == "" || strings.ContainsAny(tag, " \t\n") || strings.HasPrefix(tag, "-") {
+		b.errorf("Invalid tag %q", tag)
+		return
+	}
+	if b.search.Tags != "" {
+		b.search.Tags += " "
+	}
+	b.search.Tags += prefix + tag
+}
+
+// WithParameter restricts the search to executions having the parameter with the given value
+func (b *SearchBuilder) WithParameter(name, value string) *SearchBuilder {
+	if name == "" {
+		b.errorf("Parameter name must not be empty")
+	}
+	b.search.Parameters = append(b.search.Parameters, CriteriaParameter{Name: name, Value: value})
+	return b
+}
+
+// ExecutedAfter restricts the search to executions started after the given time
+func (b *SearchBuilder) ExecutedAfter(t time.Time) *SearchBuilder {
+	b.search.ExecutedAfter = &JaxbTime{Time: t}
+	return b
+}
+
+// ExecutedBefore restricts the search to executions started before the given time
+func (b *SearchBuilder) ExecutedBefore(t time.Time) *SearchBuilder {
+	b.search.ExecutedBefore = &JaxbTime{Time: t}
+	return b
+}
+
+// ExecutedBetween restricts the search to executions started between from and to
+func (b *SearchBuilder) ExecutedBetween(from, to time.Time) *SearchBuilder {
+	return b.ExecutedAfter(from).ExecutedBefore(to)
+}
+
+// InGroups sets whose executions are searched
+func (b *SearchBuilder) InGroups(filter GroupFilter) *SearchBuilder {
+	b.search.GroupFilter = filter
+	return b
+}
+
+// OrderBy sets the order of the results. Use OrderByParameter for ordering by
+// a parameter value.
+func (b *SearchBuilder) OrderBy(order OrderBy) *SearchBuilder {
+	if order == ParameterAscOrderBy || order == ParameterDescOrderBy {
+		b.errorf("Use OrderByParameter to order by %s", order.String())
+	}
+	b.search.OrderBy = order
+	b.search.OrderByParameter = ""
+	return b
+}
+
+// OrderByParameter orders the results by the value of the given execution parameter
+func (b *SearchBuilder) OrderByParameter(name string, descending bool) *SearchBuilder {
+	if name == "" {
+		b.errorf("Parameter name for ordering must not be empty")
+	}
+	b.search.OrderBy = ParameterAscOrderBy
+	if descending {
+		b.search.OrderBy = ParameterDescOrderBy
+	}
+	b.search.OrderByParameter = name
+	return b
+}
+
+// LabelParameter sets the execution parameter whose value PerfRepo uses as a label
+// of the executions instead of their names
+func (b *SearchBuilder) LabelParameter(name string) *SearchBuilder {
+	if name == "" {
+		b.errorf("Label parameter must not be empty")
+	}
+	b.search.LabelParameter = name
+	return b
+}
+
+// Page restricts the results to howMany executions starting at the zero-based offset from
+func (b *SearchBuilder) Page(from, howMany int) *SearchBuilder {
+	if from < 0 || howMany <= 0 {
+		b.errorf("Invalid page from %d, how many %d", from, howMany)
+	}
+	b.search.LimitFrom = from
+	b.search.HowMany = howMany
+	return b
+}
+
+// Build validates the collected criteria and returns the resulting TestExecutionSearch
+func (b *SearchBuilder) Build() (*TestExecutionSearch, error) {
+	errs := append([]string(nil), b.errs...)
+	s := b.search
+	if s.ExecutedAfter != nil && s.ExecutedBefore != nil &&
+		!s.ExecutedAfter.Before(s.ExecutedBefore.Time) {
+		errs = append(errs, "Executed after must be earlier than executed before")
+	}
+	if s.LimitFrom > 0 && s.HowMany == 0 {
+		errs = append(errs, "Page size is required when page offset is set")
+	}
+	if len(errs) > 0 {
+		return nil, errors.New("Invalid search: " + strings.Join(errs, "; "))
+	}
+	if s.IDS != nil {
+		ids := append([]int64(nil), *s.IDS...)
+		s.IDS = &ids
+	}
+	s.Parameters = append([]CriteriaParameter(nil), s.Parameters...)
+	return &s, nil
+}
+
+// Err returns the problems collected so far or nil when there are none. Build reports them as well,
+// Err allows attributing them to the call which caused them.
+func (b *SearchBuilder) Err() error {
+	if len(b.errs) == 0 {
+		return nil
+	}
+	return errors.New(strings.Join(b.errs, "; "))
+}
+
+func (b *SearchBuilder) errorf(format string, args ...interface{}) {
+	b.errs = append(b.errs, fmt.Sprintf(format, args...))
+}
@@ -40,6 +40,11 @@ var orderByValues = []string{"Unknown", "DATE_ASC", "DATE_DESC", "PARAMETER_ASC"
 	"NAME_ASC", "NAME_DESC", "UID_ASC", "UID_DESC", "GROUP_ID_ASC",
 	"GROUP_ID_DESC"}
 
+// TestExecutionSearch holds criteria for the SearchTestExecutions operation. Tags contains
+// space separated tag names, a tag prefixed with "-" excludes executions having it.
+// LabelParameter names the execution parameter whose value PerfRepo uses as a label of
+// the executions. OrderByParameter is only used with ParameterAscOrderBy and ParameterDescOrderBy.
+// Use NewSearch to construct validated criteria.
 type TestExecutionSearch struct {
 	XMLName          xml.Name            `xml:"test-execution-search"`
 	GroupFilter      GroupFilter         `xml:"group-filter,omitempty"`
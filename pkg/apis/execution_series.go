@@ -0,0 +1,186 @@
+package apis
+
+import (
+	"math"
+	"sort"
+	"strconv"
+)
+
+// SeriesPoint is a single value of a multi-value metric
+type SeriesPoint struct {
+	Key    string // value of the parameter the series is keyed by
+	Result float64
+	Value  *Value
+}
+
+// Series holds values of a metric keyed by one of their value parameters, e.g. client=1, client=2
+type Series struct {
+	Metric    string
+	Parameter string
+	Points    []SeriesPoint // ordered by key, see LessParameterValue
+}
+
+// Keys returns keys of the points in order
+func (s *Series) Keys() []string {
+	keys := make([]string, len(s.Points))
+	for i, p := range s.Points {
+		keys[i] = p.Key
+	}
+	return keys
+}
+
+// Results returns results of the points in order
+func (s *Series) Results() []float64 {
+	results := make([]float64, len(s.Points))
+	for i, p := range s.Points {
+		results[i] = p.Result
+	}
+	return results
+}
+
+// Result returns the result of the first point with the given key
+func (s *Series) Result(key string) (float64, bool) {
+	for _, p := range s.Points {
+		if p.Key == key {
+			return p.Result, true
+		}
+	}
+	return 0, false
+}
+
+// MetricValues returns values of the metric
+func (t *TestExecution) MetricValues(metric string) []Value {
+	var values []Value
+	for _, v := range t.Values {
+		if v.MetricName == metric {
+			values = append(values, v)
+		}
+	}
+	return values
+}
+
+// Series returns values of the metric which have the value parameter, keyed by the value of
+// the parameter. Points are ordered by key with numeric keys compared as numbers.
+func (t *TestExecution) Series(metric, parameter string) *Series {
+	s := &Series{Metric: metric, Parameter: parameter}
+	for i := range t.Values {
+		v := &t.Values[i]
+		if v.MetricName != metric {
+			continue
+		}
+		for _, p := range v.Parameters {
+			if p.Name == parameter {
+				s.Points = append(s.Points, SeriesPoint{Key: p.Value, Result: v.Result, Value: v})
+				break
+			}
+		}
+	}
+	sort.SliceStable(s.Points, func(i, j int) bool {
+		return LessParameterValue(s.Points[i].Key, s.Points[j].Key)
+	})
+	return s
+}
+
+// ExecutionSeries is the series of a single execution within a set of executions
+type ExecutionSeries struct {
+	Execution *TestExecution
+	Series    *Series
+}
+
+// SeriesOf returns the series of the metric keyed by the value parameter for each of the executions
+// having at least one such value, in order of the executions
+func SeriesOf(executions []TestExecution, metric, parameter string) []ExecutionSeries {
+	var result []ExecutionSeries
+	for i := range executions {
+		s := executions[i].Series(metric, parameter)
+		if len(s.Points) > 0 {
+			result = append(result, ExecutionSeries{Execution: &executions[i], Series: s})
+		}
+	}
+	return result
+}
+
+// SeriesTable is a pivot of series of several executions. Each row belongs to an execution and
+// each column to a value of the parameter.
+type SeriesTable struct {
+	Metric    string
+	Parameter string
+	Columns   []string // keys of all the series ordered by LessParameterValue
+	Rows      []SeriesRow
+}
+
+// SeriesRow holds results of an execution. Cells are aligned with columns of the table, missing
+// results are NaN.
+type SeriesRow struct {
+	Execution *TestExecution
+	Cells     []float64
+}
+
+// Column returns the results of the column with the given key, one per row
+func (t *SeriesTable) Column(key string) []float64 {
+	for i, c := range t.Columns {
+		if c == key {
+			column := make([]float64, len(t.Rows))
+			for j, r := range t.Rows {
+				column[j] = r.Cells[i]
+			}
+			return column
+		}
+	}
+	return nil
+}
+
+// PivotSeries pivots series of the metric keyed by the value parameter into a table with a row for
+// each of the executions having at least one such value
+func PivotSeries(executions []TestExecution, metric, parameter string) *SeriesTable {
+	table := &SeriesTable{Metric: metric, Parameter: parameter}
+	series := SeriesOf(executions, metric, parameter)
+	seen := make(map[string]bool)
+	for _, es := range series {
+		for _, p := range es.Series.Points {
+			if !seen[p.Key] {
+				seen[p.Key] = true
+				table.Columns = append(table.Columns, p.Key)
+			}
+		}
+	}
+	sort.SliceStable(table.Columns, func(i, j int) bool {
+		return LessParameterValue(table.Columns[i], table.Columns[j])
+	})
+	columns := make(map[string]int)
+	for i, c := range table.Columns {
+		columns[c] = i
+	}
+	for _, es := range series {
+		row := SeriesRow{Execution: es.Execution, Cells: make([]float64, len(table.Columns))}
+		for i := range row.Cells {
+			row.Cells[i] = math.NaN()
+		}
+		for j := len(es.Series.Points) - 1; j >= 0; j-- {
+			//the first point wins when there are more with the same key
+			p := es.Series.Points[j]
+			row.Cells[columns[p.Key]] = p.Result
+		}
+		table.Rows = append(table.Rows, row)
+	}
+	return table
+}
+
+// LessParameterValue orders values of parameters so that numbers are compared by their value
+// and precede other values, which are compared as strings
+func LessParameterValue(a, b string) bool {
+	fa, errA := strconv.ParseFloat(a, 64)
+	fb, errB := strconv.ParseFloat(b, 64)
+	switch {
+	case errA == nil && errB == nil:
+		if fa != fb {
+			return fa < fb
+		}
+		return a < b
+	case errA == nil:
+		return true
+	case errB == nil:
+		return false
+	}
+	return a < b
+}
@@ -0,0 +1,57 @@
+package apis
+
+import (
+	"math"
+	"reflect"
+	"testing"
+)
+
+func multiValueExecution(results map[string]float64) TestExecution {
+	exec := TestExecution{Values: []Value{{MetricName: "metric1", Result: 1}}}
+	for client, result := range results {
+		exec.Values = append(exec.Values, Value{
+			MetricName: "multimetric",
+			Result:     result,
+			Parameters: []ValueParameter{{Name: "client", Value: client}},
+		})
+	}
+	return exec
+}
+
+func TestSeries(t *testing.T) {
+	exec := multiValueExecution(map[string]float64{"10": 100, "2": 20, "1": 10, "all": 1})
+	s := exec.Series("multimetric", "client")
+	if keys := s.Keys(); !reflect.DeepEqual(keys, []string{"1", "2", "10", "all"}) {
+		t.Errorf("Unexpected order of keys %v", keys)
+	}
+	if results := s.Results(); !reflect.DeepEqual(results, []float64{10, 20, 100, 1}) {
+		t.Errorf("Unexpected results %v", results)
+	}
+	if r, ok := s.Result("2"); !ok || r != 20 {
+		t.Errorf("Unexpected result for client=2: %v", r)
+	}
+	if len(exec.Series("metric1", "client").Points) != 0 {
+		t.Error("Values without the parameter included in series")
+	}
+}
+
+func TestPivotSeries(t *testing.T) {
+	executions := []TestExecution{
+		multiValueExecution(map[string]float64{"1": 10, "2": 20}),
+		{Values: []Value{{MetricName: "metric1", Result: 1}}},
+		multiValueExecution(map[string]float64{"2": 21, "16": 160}),
+	}
+	table := PivotSeries(executions, "multimetric", "client")
+	if !reflect.DeepEqual(table.Columns, []string{"1", "2", "16"}) {
+		t.Fatalf("Unexpected columns %v", table.Columns)
+	}
+	if len(table.Rows) != 2 || table.Rows[1].Execution != &executions[2] {
+		t.Fatalf("Unexpected rows %+v", table.Rows)
+	}
+	if c := table.Column("2"); !reflect.DeepEqual(c, []float64{20, 21}) {
+		t.Errorf("Unexpected column %v", c)
+	}
+	if !math.IsNaN(table.Rows[0].Cells[2]) || table.Rows[1].Cells[2] != 160 {
+		t.Errorf("Unexpected cells %v", table.Rows)
+	}
+}
@@ -4,6 +4,7 @@ import (
 	"encoding/xml"
 	"io"
 	"sort"
+	"strings"
 	"time"
 )
 
@@ -86,6 +87,17 @@ func (t *TestExecution) ParametersMap() map[string]string {
 	return paramsMap
 }
 
+// ParametersKey returns the value parameters as sorted name=value pairs separated by ", ".
+// Values of a multi-value metric are matched across executions by this key.
+func (v *Value) ParametersKey() string {
+	parts := make([]string, 0, len(v.Parameters))
+	for _, p := range v.Parameters {
+		parts = append(parts, p.Name+"="+p.Value)
+	}
+	sort.Strings(parts)
+	return strings.Join(parts, ", ")
+}
+
 // UnmarshalXMLAttr implements custom unmarshalling of date/time attribute compatible with default JAXB format
 func (c *JaxbTime) UnmarshalXMLAttr(attr xml.Attr) error {
 	parsed, err := time.Parse(jaxbDateFormat, attr.Value)
@@ -0,0 +1,387 @@
+package apis
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// TagExpr is a boolean expression over tags of test executions. PerfRepo only accepts
+// a list of required and excluded tags, expressions that can't be expressed this way are
+// split into several searches by TagSearches.
+type TagExpr interface {
+	String() string
+	// dnf converts the (optionally negated) expression to disjunctive normal form
+	dnf(negated bool) []tagConjunction
+}
+
+type tagLiteral struct {
+	name    string
+	negated bool
+}
+
+type tagConjunction []tagLiteral
+
+type hasTag struct {
+	name string
+}
+
+type tagNot struct {
+	expr TagExpr
+}
+
+type tagAnd struct {
+	exprs []TagExpr
+}
+
+type tagOr struct {
+	exprs []TagExpr
+}
+
+// HasTag matches executions having the tag
+func HasTag(name string) TagExpr {
+	return hasTag{name: name}
+}
+
+// TagNot matches executions not matching the expression
+func TagNot(expr TagExpr) TagExpr {
+	return tagNot{expr: expr}
+}
+
+// TagAnd matches executions matching all of the expressions
+func TagAnd(exprs ...TagExpr) TagExpr {
+	return tagAnd{exprs: exprs}
+}
+
+// TagOr matches executions matching any of the expressions
+func TagOr(exprs ...TagExpr) TagExpr {
+	return tagOr{exprs: exprs}
+}
+
+func (t hasTag) String() string {
+	return quoteTag(t.name)
+}
+
+// quoteTag quotes tag names which would otherwise be parsed as operators or split into more tokens
+func quoteTag(name string) string {
+	switch name {
+	case "", "AND", "OR", "NOT":
+		return strconv.Quote(name)
+	}
+	if strings.HasPrefix(name, "-") || strings.HasPrefix(name, `"`) ||
+		strings.IndexFunc(name, func(r rune) bool { return unicode.IsSpace(r) || r == '(' || r == ')' }) >= 0 {
+		return strconv.Quote(name)
+	}
+	return name
+}
+
+func (t hasTag) dnf(negated bool) []tagConjunction {
+	return []tagConjunction{{{name: t.name, negated: negated}}}
+}
+
+func (t tagNot) String() string {
+	return "NOT " + t.expr.String()
+}
+
+func (t tagNot) dnf(negated bool) []tagConjunction {
+	return t.expr.dnf(!negated)
+}
+
+func (t tagAnd) String() string {
+	return joinTagExprs(t.exprs, " AND ")
+}
+
+func (t tagAnd) dnf(negated bool) []tagConjunction {
+	if negated { //NOT (a AND b) == NOT a OR NOT b
+		return unionDNF(t.exprs, true)
+	}
+	return productDNF(t.exprs, false)
+}
+
+func (t tagOr) String() string {
+	return joinTagExprs(t.exprs, " OR ")
+}
+
+func (t tagOr) dnf(negated bool) []tagConjunction {
+	if negated { //NOT (a OR b) == NOT a AND NOT b
+		return productDNF(t.exprs, true)
+	}
+	return unionDNF(t.exprs, false)
+}
+
+func joinTagExprs(exprs []TagExpr, sep string) string {
+	parts := make([]string, 0, len(exprs))
+	for _, e := range exprs {
+		parts = append(parts, e.String())
+	}
+	return "(" + strings.Join(parts, sep) + ")"
+}
+
+func unionDNF(exprs []TagExpr, negated bool) []tagConjunction {
+	var result []tagConjunction
+	for _, e := range exprs {
+		result = append(result, e.dnf(negated)...)
+	}
+	return result
+}
+
+func productDNF(exprs []TagExpr, negated bool) []tagConjunction {
+	result := []tagConjunction{{}}
+	for _, e := range exprs {
+		var next []tagConjunction
+		for _, left := range result {
+			for _, right := range e.dnf(negated) {
+				conj := append(append(tagConjunction(nil), left...), right...)
+				next = append(next, conj)
+			}
+		}
+		result = next
+	}
+	return result
+}
+
+// TagSearches converts the expression to values of the TestExecutionSearch.Tags criteria.
+// The union of results of searches with these values matches the expression. Returns no
+// values when the expression can't match any execution.
+func TagSearches(expr TagExpr) ([]string, error) {
+	if expr == nil {
+		return nil, errors.New("Missing tag expression")
+	}
+	var conjunctions []map[tagLiteral]bool
+	for _, conj := range expr.dnf(false) {
+		literals := make(map[tagLiteral]bool)
+		contradiction := false
+		for _, l := range conj {
+			if err := validateTagName(l.name); err != nil {
+				return nil, err
+			}
+			literals[l] = true
+			if literals[tagLiteral{name: l.name, negated: !l.negated}] {
+				contradiction = true
+			}
+		}
+		if !contradiction {
+			conjunctions = append(conjunctions, literals)
+		}
+	}
+
+	//a conjunction is redundant when another conjunction requires a subset of its tags
+	var searches []string
+	seen := make(map[string]bool)
+	for i, conj := range conjunctions {
+		redundant := false
+		for j, other := range conjunctions {
+			if i != j && isSubset(other, conj) && (len(other) < len(conj) || j < i) {
+				redundant = true
+				break
+			}
+		}
+		if redundant {
+			continue
+		}
+		search := tagsCriteria(conj)
+		if !seen[search] {
+			seen[search] = true
+			searches = append(searches, search)
+		}
+	}
+	return searches, nil
+}
+
+func isSubset(sub, super map[tagLiteral]bool) bool {
+	for l := range sub {
+		if !super[l] {
+			return false
+		}
+	}
+	return true
+}
+
+func tagsCriteria(literals map[tagLiteral]bool) string {
+	parts := make([]string, 0, len(literals))
+	for l := range literals {
+		if l.negated {
+			parts = append(parts, "-"+l.name)
+		} else {
+			parts = append(parts, l.name)
+		}
+	}
+	sort.Strings(parts)
+	return strings.Join(parts, " ")
+}
+
+func validateTagName(name string) error {
+	if name == "" || strings.HasPrefix(name, "-") || strings.IndexFunc(name, unicode.IsSpace) >= 0 {
+		return fmt.Errorf("Invalid tag %q", name)
+	}
+	return nil
+}
+
+// ParseTagExpr parses tag expressions such as "nightly AND NOT (invalid OR flaky)".
+// Supported operators are AND, OR and NOT; tags separated only by whitespace are combined
+// with AND and a tag prefixed with "-" is negated. Parentheses group subexpressions.
+// Tags named like operators or containing whitespace or parentheses are written as double-quoted
+// Go string literals, e.g. "nightly AND NOT \"NOT\"". PerfRepo can't search tags containing
+// whitespace though, TagSearches rejects them.
+func ParseTagExpr(input string) (TagExpr, error) {
+	tokens, err := tokenizeTagExpr(input)
+	if err != nil {
+		return nil, err
+	}
+	p := &tagParser{tokens: tokens}
+	expr, err := p.parseOr()
+	if err != nil {
+		return nil, err
+	}
+	if t := p.peek(); !t.end() {
+		return nil, fmt.Errorf("Unexpected %q at position %d", t.text, t.pos)
+	}
+	return expr, nil
+}
+
+type tagToken struct {
+	text   string
+	pos    int
+	quoted bool // quoted tokens are always tag names
+}
+
+// end returns true for the token marking the end of the input
+func (t tagToken) end() bool {
+	return t.text == "" && !t.quoted
+}
+
+// operator returns true when the token is the given operator or parenthesis
+func (t tagToken) operator(op string) bool {
+	return t.text == op && !t.quoted
+}
+
+func tokenizeTagExpr(input string) ([]tagToken, error) {
+	var tokens []tagToken
+	start := -1
+	flush := func(end int) {
+		if start >= 0 {
+			tokens = append(tokens, tagToken{text: input[start:end], pos: start})
+			start = -1
+		}
+	}
+	for i := 0; i < len(input); {
+		r, size := utf8.DecodeRuneInString(input[i:])
+		switch {
+		case unicode.IsSpace(r):
+			flush(i)
+		case r == '(' || r == ')':
+			flush(i)
+			tokens = append(tokens, tagToken{text: string(r), pos: i})
+		case r == '-' && start < 0:
+			tokens = append(tokens, tagToken{text: "-", pos: i})
+		case r == '"' && start < 0:
+			literal, err := strconv.QuotedPrefix(input[i:])
+			if err != nil {
+				return nil, fmt.Errorf("Unterminated quoted tag at position %d", i)
+			}
+			name, _ := strconv.Unquote(literal)
+			tokens = append(tokens, tagToken{text: name, pos: i, quoted: true})
+			size = len(literal)
+		default:
+			if start < 0 {
+				start = i
+			}
+		}
+		i += size
+	}
+	flush(len(input))
+	return append(tokens, tagToken{pos: len(input)}), nil
+}
+
+type tagParser struct {
+	tokens []tagToken
+	pos    int
+}
+
+func (p *tagParser) peek() tagToken {
+	return p.tokens[p.pos]
+}
+
+func (p *tagParser) next() tagToken {
+	t := p.tokens[p.pos]
+	if !t.end() {
+		p.pos++
+	}
+	return t
+}
+
+func (p *tagParser) parseOr() (TagExpr, error) {
+	exprs, err := p.parseSequence("OR", p.parseAnd)
+	if err != nil || len(exprs) == 1 {
+		return exprs[0], err
+	}
+	return TagOr(exprs...), nil
+}
+
+func (p *tagParser) parseAnd() (TagExpr, error) {
+	exprs, err := p.parseSequence("AND", p.parseUnary)
+	if err != nil || len(exprs) == 1 {
+		return exprs[0], err
+	}
+	return TagAnd(exprs...), nil
+}
+
+// parseSequence parses operands separated by the operator. Operands of AND may also be
+// separated by whitespace only.
+func (p *tagParser) parseSequence(operator string, operand func() (TagExpr, error)) ([]TagExpr, error) {
+	first, err := operand()
+	if err != nil {
+		return []TagExpr{nil}, err
+	}
+	exprs := []TagExpr{first}
+	for {
+		t := p.peek()
+		switch {
+		case t.operator(operator):
+			p.next()
+		case operator == "AND" && !t.end() && !t.operator(")") && !t.operator("OR"):
+		default:
+			return exprs, nil
+		}
+		e, err := operand()
+		if err != nil {
+			return []TagExpr{nil}, err
+		}
+		exprs = append(exprs, e)
+	}
+}
+
+func (p *tagParser) parseUnary() (TagExpr, error) {
+	t := p.next()
+	if t.quoted {
+		return HasTag(t.text), nil
+	}
+	switch t.text {
+	case "NOT", "-":
+		e, err := p.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+		return TagNot(e), nil
+	case "(":
+		e, err := p.parseOr()
+		if err != nil {
+			return nil, err
+		}
+		if closing := p.next(); !closing.operator(")") {
+			return nil, fmt.Errorf("Missing closing parenthesis for position %d at position %d", t.pos, closing.pos)
+		}
+		return e, nil
+	case "", ")", "AND", "OR":
+		if t.text == "" {
+			return nil, fmt.Errorf("Unexpected end of expression at position %d", t.pos)
+		}
+		return nil, fmt.Errorf("Unexpected %q at position %d", t.text, t.pos)
+	default:
+		return HasTag(t.text), nil
+	}
+}
@@ -0,0 +1,70 @@
+package apis
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestTagSearches(t *testing.T) {
+	cases := []struct {
+		expr     string
+		searches []string
+	}{
+		{"nightly", []string{"nightly"}},
+		{"nightly -invalid", []string{"-invalid nightly"}},
+		{"nightly AND NOT invalid", []string{"-invalid nightly"}},
+		{"nightly OR weekly", []string{"nightly", "weekly"}},
+		{"nightly AND NOT (invalid OR flaky)", []string{"-flaky -invalid nightly"}},
+		{"(a OR b) AND (c OR d)", []string{"a c", "a d", "b c", "b d"}},
+		{"NOT (a AND b)", []string{"-a", "-b"}},
+		{"a OR (a AND b)", []string{"a"}},
+		{"a AND -a", nil},
+		{"a OR a", []string{"a"}},
+		{`"NOT" AND NOT "AND"`, []string{"-AND NOT"}},
+		{`nightly -"OR"`, []string{"-OR nightly"}},
+		{`"(beta)"`, []string{"(beta)"}},
+	}
+	for _, c := range cases {
+		expr, err := ParseTagExpr(c.expr)
+		if err != nil {
+			t.Fatalf("Failed to parse %q: %v", c.expr, err)
+		}
+		searches, err := TagSearches(expr)
+		if err != nil {
+			t.Fatalf("Failed to convert %q: %v", c.expr, err)
+		}
+		if !reflect.DeepEqual(searches, c.searches) {
+			t.Errorf("Searches for %q: expected %q, got %q", c.expr, c.searches, searches)
+		}
+	}
+}
+
+func TestParseTagExprErrors(t *testing.T) {
+	for _, input := range []string{"", "(a", "a)", "a AND", "OR a", "a AND OR b", `"a`, `a AND "b`} {
+		if expr, err := ParseTagExpr(input); err == nil {
+			t.Errorf("Expected error for %q, got %v", input, expr)
+		}
+	}
+}
+
+func TestTagExprRoundTrip(t *testing.T) {
+	expr := TagAnd(HasTag("nightly"), TagNot(TagOr(HasTag("invalid"), HasTag("flaky"), HasTag("OR"),
+		HasTag("not (yet)"), HasTag(`-say "hi"`))))
+	parsed, err := ParseTagExpr(expr.String())
+	if err != nil {
+		t.Fatalf("Failed to parse %q: %v", expr.String(), err)
+	}
+	if parsed.String() != expr.String() {
+		t.Errorf("Expected %q, got %q", expr.String(), parsed.String())
+	}
+}
+
+func TestTagSearchesWhitespace(t *testing.T) {
+	expr, err := ParseTagExpr(`nightly AND "two words"`)
+	if err != nil {
+		t.Fatal("Failed to parse quoted tag", err)
+	}
+	if _, err := TagSearches(expr); err == nil {
+		t.Error("Expected error for a tag with whitespace")
+	}
+}
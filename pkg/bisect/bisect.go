@@ -0,0 +1,371 @@
+// Package bisect helps to find the commit which caused a regression between a good and a bad
+// execution. Commits between them are read from a local git repository, commits which already have
+// executions are classified as good or bad and the next commits to benchmark are proposed.
+// The state, including commits marked manually, is kept in a file across runs.
+package bisect
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"math"
+	"os"
+	"os/exec"
+	"regexp"
+	"strings"
+
+	"github.com/pkg/errors"
+
+	"github.com/mgencur/go-perfrepoclient/pkg/apis"
+	"github.com/mgencur/go-perfrepoclient/pkg/client"
+	"github.com/mgencur/go-perfrepoclient/pkg/stats"
+)
+
+// DefaultCommitParameter is the execution parameter holding the commit when Options.CommitParameter is empty
+const DefaultCommitParameter = "git_commit"
+
+// MinCommitPrefix is the minimal length of an abbreviated commit hash
+const MinCommitPrefix = 7
+
+// commitPattern matches full or abbreviated hashes of SHA-1 and SHA-256 repositories
+var commitPattern = regexp.MustCompile(fmt.Sprintf("^[0-9a-fA-F]{%d,64}$", MinCommitPrefix))
+
+// Status is the classification of a commit
+type Status int
+
+// enumerate values for Status
+const (
+	Untested Status = iota
+	Good
+	Bad
+	Skip // the commit can't be benchmarked, e.g. it doesn't build
+)
+
+var statusValues = []string{"untested", "good", "bad", "skip"}
+
+func (s *Status) String() string {
+	return statusValues[*s]
+}
+
+// ParseStatus converts string to its enum representation
+func ParseStatus(value string) (Status, error) {
+	for i, v := range statusValues {
+		if v == value {
+			return Status(i), nil
+		}
+	}
+	return Untested, errors.New("Unable to parse " + value)
+}
+
+// MarshalText implements encoding.TextMarshaler
+func (s Status) MarshalText() ([]byte, error) {
+	return []byte(s.String()), nil
+}
+
+// UnmarshalText implements encoding.TextUnmarshaler
+func (s *Status) UnmarshalText(text []byte) error {
+	parsed, err := ParseStatus(string(text))
+	if err != nil {
+		return err
+	}
+	*s = parsed
+	return nil
+}
+
+// Options configures the bisection
+type Options struct {
+	Repository      string // path to the local git repository
+	StateFile       string // file keeping the state across runs, the state is not persisted when empty
+	CommitParameter string // execution parameter holding the commit, DefaultCommitParameter when empty
+	Metric          string // metric deciding whether a commit is good or bad
+	Parameters      string // value parameters of a multi-value metric, see apis.Value.ParametersKey
+	Parallel        int    // number of commits proposed at once, 1 by default
+}
+
+// State is the persisted state of a bisection
+type State struct {
+	Good    string            `json:"good"`
+	Bad     string            `json:"bad"`
+	Commits []string          `json:"commits"` // commits from good to bad, both included
+	Marks   map[string]Status `json:"marks"`   // commits marked manually
+}
+
+// Step holds the outcome of a bisection step
+type Step struct {
+	Statuses map[string]Status  // status of each commit, manual marks take precedence over measurements
+	Results  map[string]float64 // median result of the metric of commits with executions
+	LastGood string
+	FirstBad string
+	// Remaining is the number of commits after LastGood up to FirstBad which may have caused the regression
+	Remaining int
+	Culprit   string   // the first bad commit when the bisection is finished
+	Next      []string // commits to benchmark next
+}
+
+// Bisector performs a bisection between two executions
+type Bisector struct {
+	client    *client.PerfRepoClient
+	opts      Options
+	good      *apis.TestExecution
+	bad       *apis.TestExecution
+	goodValue float64
+	badValue  float64
+	state     *State
+}
+
+// New starts a new bisection between the good and the bad execution or resumes the one kept
+// in the state file when it was started for the same commits
+func New(c *client.PerfRepoClient, good, bad *apis.TestExecution, opts Options) (*Bisector, error) {
+	if opts.CommitParameter == "" {
+		opts.CommitParameter = DefaultCommitParameter
+	}
+	if opts.Parallel <= 0 {
+		opts.Parallel = 1
+	}
+	b := &Bisector{client: c, opts: opts, good: good, bad: bad}
+
+	goodCommit, badCommit := b.commitOf(good), b.commitOf(bad)
+	if goodCommit == "" || badCommit == "" {
+		return nil, errors.Errorf("Executions must have parameter %s", opts.CommitParameter)
+	}
+	var ok bool
+	if b.goodValue, ok = b.resultOf(good); !ok {
+		return nil, errors.Errorf("Good execution has no value of metric %s", opts.Metric)
+	}
+	if b.badValue, ok = b.resultOf(bad); !ok {
+		return nil, errors.Errorf("Bad execution has no value of metric %s", opts.Metric)
+	}
+
+	state, err := loadState(opts.StateFile)
+	if err != nil {
+		return nil, err
+	}
+	if state == nil || state.Good != goodCommit || state.Bad != badCommit {
+		commits, err := listCommits(opts.Repository, goodCommit, badCommit)
+		if err != nil {
+			return nil, err
+		}
+		state = &State{Good: goodCommit, Bad: badCommit, Commits: commits, Marks: make(map[string]Status)}
+	}
+	b.state = state
+	return b, b.save()
+}
+
+// State returns the current state of the bisection
+func (b *Bisector) State() *State {
+	return b.state
+}
+
+// Mark classifies the commit manually, e.g. as Skip when it can't be benchmarked. Marking
+// a commit as Untested removes the mark.
+func (b *Bisector) Mark(commit string, status Status) error {
+	commit, err := b.resolve(commit)
+	if err != nil {
+		return err
+	}
+	if status == Untested {
+		delete(b.state.Marks, commit)
+	} else {
+		b.state.Marks[commit] = status
+	}
+	return b.save()
+}
+
+// Next searches executions of the test of the bad execution and performs a bisection step
+func (b *Bisector) Next() (*Step, error) {
+	executions, err := b.client.SearchTestExecutions(&apis.TestExecutionSearch{TestUID: b.bad.TestUID})
+	if err != nil {
+		return nil, errors.Wrap(err, "Failed to fetch executions for bisection")
+	}
+	return b.Advance(executions), nil
+}
+
+// Advance classifies commits of the executions by their result, which is good when it's closer
+// to the result of the good execution, and proposes the next commits to benchmark. Executions
+// whose commit is outside of the bisected range or ambiguous are ignored.
+func (b *Bisector) Advance(executions []apis.TestExecution) *Step {
+	results := make(map[string][]float64)
+	for i := range executions {
+		commit, err := b.resolve(b.commitOf(&executions[i]))
+		if err != nil {
+			continue
+		}
+		if r, ok := b.resultOf(&executions[i]); ok {
+			results[commit] = append(results[commit], r)
+		}
+	}
+
+	step := &Step{Statuses: make(map[string]Status), Results: make(map[string]float64)}
+	for commit, rs := range results {
+		median := stats.Median(rs)
+		step.Results[commit] = median
+		if math.Abs(median-b.goodValue) < math.Abs(median-b.badValue) {
+			step.Statuses[commit] = Good
+		} else {
+			step.Statuses[commit] = Bad
+		}
+	}
+	for commit, status := range b.state.Marks {
+		step.Statuses[commit] = status
+	}
+	commits := b.state.Commits
+	step.Statuses[commits[0]] = Good
+	step.Statuses[commits[len(commits)-1]] = Bad
+
+	lastGood, firstBad := 0, len(commits)-1
+	for i, c := range commits {
+		if step.Statuses[c] == Bad {
+			firstBad = i
+			break
+		}
+	}
+	for i := firstBad - 1; i >= 0; i-- {
+		if step.Statuses[commits[i]] == Good {
+			lastGood = i
+			break
+		}
+	}
+	step.LastGood, step.FirstBad = commits[lastGood], commits[firstBad]
+	step.Remaining = firstBad - lastGood
+
+	var untested []int
+	for i := lastGood + 1; i < firstBad; i++ {
+		if step.Statuses[commits[i]] == Untested {
+			untested = append(untested, i)
+		}
+	}
+	if len(untested) == 0 {
+		//the culprit is known exactly only when no skipped commits remain in between
+		if step.Remaining == 1 {
+			step.Culprit = step.FirstBad
+		}
+		return step
+	}
+	//split the untested commits evenly into Parallel+1 parts
+	parts := b.opts.Parallel + 1
+	seen := make(map[int]bool)
+	for k := 1; k < parts && k <= len(untested); k++ {
+		i := untested[k*len(untested)/parts]
+		if !seen[i] {
+			seen[i] = true
+			step.Next = append(step.Next, commits[i])
+		}
+	}
+	return step
+}
+
+func (b *Bisector) commitOf(exec *apis.TestExecution) string {
+	return exec.ParametersMap()[b.opts.CommitParameter]
+}
+
+func (b *Bisector) resultOf(exec *apis.TestExecution) (float64, bool) {
+	for _, v := range exec.Values {
+		if v.MetricName == b.opts.Metric && v.ParametersKey() == b.opts.Parameters {
+			return v.Result, true
+		}
+	}
+	return 0, false
+}
+
+// resolve returns the commit of the bisected range identified by the full or abbreviated hash
+func (b *Bisector) resolve(commit string) (string, error) {
+	if !commitPattern.MatchString(commit) {
+		return "", errors.Errorf("Invalid commit %q, expected at least %d hexadecimal digits",
+			commit, MinCommitPrefix)
+	}
+	commit = strings.ToLower(commit)
+	var matches []string
+	for _, c := range b.state.Commits {
+		if strings.HasPrefix(c, commit) {
+			matches = append(matches, c)
+		}
+	}
+	switch len(matches) {
+	case 0:
+		return "", errors.Errorf("Commit %s is not within the bisected range", commit)
+	case 1:
+		return matches[0], nil
+	default:
+		return "", errors.Errorf("Commit %s is ambiguous, it matches %s", commit,
+			strings.Join(matches, ", "))
+	}
+}
+
+func (b *Bisector) save() error {
+	if b.opts.StateFile == "" {
+		return nil
+	}
+	data, err := json.MarshalIndent(b.state, "", "  ")
+	if err != nil {
+		return errors.Wrap(err, "Failed to save bisection state")
+	}
+	return errors.Wrap(ioutil.WriteFile(b.opts.StateFile, data, 0644), "Failed to save bisection state")
+}
+
+// loadState reads the state file, returns nil when it doesn't exist and an error when its commits
+// don't range from its good to its bad commit
+func loadState(path string) (*State, error) {
+	if path == "" {
+		return nil, nil
+	}
+	data, err := ioutil.ReadFile(path)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, errors.Wrap(err, "Failed to load bisection state")
+	}
+	var state State
+	if err := json.Unmarshal(data, &state); err != nil {
+		return nil, errors.Wrap(err, "Failed to load bisection state")
+	}
+	//the state may have been edited by hand
+	n := len(state.Commits)
+	if n < 2 || !strings.HasPrefix(state.Commits[0], strings.ToLower(state.Good)) ||
+		!strings.HasPrefix(state.Commits[n-1], strings.ToLower(state.Bad)) {
+		return nil, errors.Errorf("Invalid bisection state %s, commits must range from the good commit %s "+
+			"to the bad commit %s", path, state.Good, state.Bad)
+	}
+	if state.Marks == nil {
+		state.Marks = make(map[string]Status)
+	}
+	return &state, nil
+}
+
+// listCommits returns full hashes of commits from good to bad, both included, oldest first
+func listCommits(repository, good, bad string) ([]string, error) {
+	//the commits come from execution parameters, make sure git doesn't take them for options
+	for _, c := range []string{good, bad} {
+		if !commitPattern.MatchString(c) {
+			return nil, errors.Errorf("Invalid commit %q, expected at least %d hexadecimal digits",
+				c, MinCommitPrefix)
+		}
+	}
+	goodHash, err := git(repository, "rev-parse", "--verify", good+"^{commit}")
+	if err != nil {
+		return nil, err
+	}
+	out, err := git(repository, "rev-list", "--reverse", "--ancestry-path", good+".."+bad)
+	if err != nil {
+		return nil, err
+	}
+	commits := []string{goodHash}
+	commits = append(commits, strings.Fields(out)...)
+	if len(commits) < 2 {
+		return nil, errors.Errorf("Commit %s is not a descendant of %s", bad, good)
+	}
+	return commits, nil
+}
+
+func git(repository string, args ...string) (string, error) {
+	cmd := exec.Command("git", append([]string{"-C", repository}, args...)...)
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+	out, err := cmd.Output()
+	if err != nil {
+		return "", errors.Wrapf(err, "Failed to run git %s: %s", strings.Join(args, " "),
+			strings.TrimSpace(stderr.String()))
+	}
+	return strings.TrimSpace(string(out)), nil
+}
@@ -0,0 +1,180 @@
+package bisect
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"reflect"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/mgencur/go-perfrepoclient/pkg/apis"
+)
+
+// repository creates a git repository with the given number of commits and returns their hashes
+func repository(t *testing.T, dir string, count int) []string {
+	run := func(args ...string) string {
+		out, err := git(dir, args...)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return out
+	}
+	if err := exec.Command("git", "init", "-q", dir).Run(); err != nil {
+		t.Skip("git is not available")
+	}
+	var commits []string
+	for i := 0; i < count; i++ {
+		run("-c", "user.name=test", "-c", "user.email=test@example.com",
+			"commit", "-q", "--allow-empty", "-m", "commit "+strconv.Itoa(i))
+		commits = append(commits, run("rev-parse", "HEAD"))
+	}
+	return commits
+}
+
+func execution(commit string, latency float64) apis.TestExecution {
+	return apis.TestExecution{
+		Parameters: []apis.TestExecutionParameter{{Name: DefaultCommitParameter, Value: commit}},
+		Values:     []apis.Value{{MetricName: "latency", Result: latency}},
+	}
+}
+
+func TestBisect(t *testing.T) {
+	dir, err := ioutil.TempDir("", "bisect")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	commits := repository(t, dir, 10)
+
+	good := execution(commits[0][:8], 100)
+	bad := execution(commits[9], 150)
+	opts := Options{Repository: dir, StateFile: filepath.Join(dir, "state.json"), Metric: "latency"}
+	b, err := New(nil, &good, &bad, opts)
+	if err != nil {
+		t.Fatal("Failed to start bisection", err)
+	}
+
+	//the regression was introduced by commits[6]
+	executions := []apis.TestExecution{good, bad}
+	for i := 0; i < 5; i++ {
+		step := b.Advance(executions)
+		if step.Culprit != "" {
+			if step.Culprit != commits[6] || step.LastGood != commits[5] {
+				t.Fatalf("Unexpected culprit %+v", step)
+			}
+			return
+		}
+		if len(step.Next) != 1 {
+			t.Fatalf("Expected a single proposal, got %+v", step)
+		}
+		next := step.Next[0]
+		latency := 100.0
+		for i, c := range commits {
+			if c == next && i >= 6 {
+				latency = 149
+			}
+		}
+		if next == commits[4] {
+			//commits[4] doesn't build, resume from the state file
+			b, err = New(nil, &good, &bad, opts)
+			if err != nil || b.Mark(next[:7], Skip) != nil {
+				t.Fatal("Failed to mark commit", err)
+			}
+			continue
+		}
+		executions = append(executions, execution(next, latency))
+	}
+	t.Fatal("Bisection didn't finish")
+}
+
+func TestAdvance(t *testing.T) {
+	//the hashes share a prefix, only the last two digits differ
+	var commits []string
+	for i := 0; i < 10; i++ {
+		commits = append(commits, strings.Repeat("ab", 19)+fmt.Sprintf("%02d", i))
+	}
+	b := &Bisector{
+		opts:      Options{Metric: "latency", CommitParameter: DefaultCommitParameter, Parallel: 3},
+		goodValue: 100,
+		badValue:  150,
+		state:     &State{Commits: commits, Marks: make(map[string]Status)},
+	}
+
+	step := b.Advance(nil)
+	if !reflect.DeepEqual(step.Next, []string{commits[3], commits[5], commits[7]}) || step.Remaining != 9 {
+		t.Fatalf("Expected 3 evenly split proposals, got %+v", step)
+	}
+
+	if err := b.Mark(strings.ToUpper(commits[6]), Skip); err != nil {
+		t.Fatal("Failed to mark commit", err)
+	}
+	for _, commit := range []string{"--help", "abab", commits[0][:7], "0123456789"} {
+		if err := b.Mark(commit, Skip); err == nil {
+			t.Errorf("Expected an error for marking %q", commit)
+		}
+	}
+	executions := []apis.TestExecution{
+		execution(commits[3], 100), execution(commits[5], 101), execution(commits[7], 149),
+		//invalid, ambiguous and unknown commits are ignored
+		execution("--all", 150), execution(commits[2][:10], 150), execution("fedcba9", 150),
+	}
+	step = b.Advance(executions)
+	if step.LastGood != commits[5] || step.FirstBad != commits[7] || step.Remaining != 2 ||
+		len(step.Next) != 0 || step.Culprit != "" {
+		t.Fatalf("Expected the skipped commit to hide the culprit, got %+v", step)
+	}
+	if step.Statuses[commits[2]] != Untested || step.Statuses[commits[6]] != Skip {
+		t.Errorf("Unexpected statuses %+v", step.Statuses)
+	}
+
+	//the commit can be benchmarked after all
+	if err := b.Mark(commits[6], Untested); err != nil {
+		t.Fatal("Failed to unmark commit", err)
+	}
+	step = b.Advance(executions)
+	if !reflect.DeepEqual(step.Next, []string{commits[6]}) {
+		t.Fatalf("Expected the unmarked commit to be proposed, got %+v", step)
+	}
+	step = b.Advance(append(executions, execution(commits[6], 140)))
+	if step.Culprit != commits[6] || step.LastGood != commits[5] || step.Remaining != 1 {
+		t.Errorf("Unexpected culprit %+v", step)
+	}
+}
+
+func TestLoadState(t *testing.T) {
+	dir, err := ioutil.TempDir("", "bisect")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "state.json")
+	good, middle, bad := strings.Repeat("a", 40), strings.Repeat("b", 40), strings.Repeat("c", 40)
+
+	cases := []struct {
+		name  string
+		state string
+		valid bool
+	}{
+		{"valid", `{"good":"AAAAAAA","bad":"` + bad + `","commits":["` + good + `","` + middle + `","` + bad + `"]}`, true},
+		{"truncated", `{"good":"` + good + `","bad":"` + bad + `","commits":["` + good + `"]}`, false},
+		{"no commits", `{"good":"` + good + `","bad":"` + bad + `"}`, false},
+		{"other good commit", `{"good":"` + middle + `","bad":"` + bad + `","commits":["` + good + `","` + bad + `"]}`, false},
+		{"other bad commit", `{"good":"` + good + `","bad":"` + bad + `","commits":["` + good + `","` + middle + `"]}`, false},
+	}
+	for _, c := range cases {
+		if err := ioutil.WriteFile(path, []byte(c.state), 0644); err != nil {
+			t.Fatal(err)
+		}
+		state, err := loadState(path)
+		if c.valid && (err != nil || len(state.Commits) != 3 || state.Marks == nil) {
+			t.Errorf("%s: expected the state to be loaded, got %+v and %v", c.name, state, err)
+		}
+		if !c.valid && err == nil {
+			t.Errorf("%s: expected an error, got %+v", c.name, state)
+		}
+	}
+}
@@ -0,0 +1,323 @@
+// Package budget evaluates performance budgets declared in a JSON file, e.g.
+//
+//	{
+//	  "budgets": [
+//	    {
+//	      "test": "order-service",
+//	      "executions": "tag:nightly order:date_desc limit:3",
+//	      "rules": [
+//	        {"metric": "p99_latency", "limit": 120},
+//	        {"metric": "throughput", "maxRegression": 0.03, "baseline": "tag:release-1.4"}
+//	      ]
+//	    }
+//	  ]
+//	}
+//
+// Executions and baselines are selected by queries of the query package restricted to the test.
+// Limits and regressions respect the metric comparator: the limit is an upper bound for metrics
+// where lower is better and a lower bound for metrics where higher is better. Rules of metrics with
+// unknown comparator fail.
+package budget
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strings"
+
+	"github.com/pkg/errors"
+
+	"github.com/mgencur/go-perfrepoclient/pkg/apis"
+	"github.com/mgencur/go-perfrepoclient/pkg/client"
+	"github.com/mgencur/go-perfrepoclient/pkg/compare"
+	"github.com/mgencur/go-perfrepoclient/pkg/query"
+	"github.com/mgencur/go-perfrepoclient/pkg/stats"
+)
+
+// DefaultExecutions selects the executions checked by a budget when Budget.Executions is empty
+const DefaultExecutions = "order:date_desc limit:1"
+
+// File holds budgets of several tests
+type File struct {
+	Budgets []Budget `json:"budgets"`
+}
+
+// Budget holds rules for metrics of a test
+type Budget struct {
+	Test       string `json:"test"`                 // test UID
+	Executions string `json:"executions,omitempty"` // query selecting checked executions, DefaultExecutions when empty
+	Rules      []Rule `json:"rules"`
+}
+
+// Rule is a budget of a metric. Values of the metric in the selected executions are aggregated
+// by median. At least one of Limit and MaxRegression must be set.
+type Rule struct {
+	Metric string `json:"metric"`
+	// Parameters selects values of multi-value metrics by name=value pairs separated by commas in any
+	// order, e.g. "client=2, threads=4"
+	Parameters string `json:"parameters,omitempty"`
+	// Limit is the worst acceptable value
+	Limit *float64 `json:"limit,omitempty"`
+	// MaxRegression is the worst acceptable relative change against the baseline, e.g. 0.03
+	MaxRegression *float64 `json:"maxRegression,omitempty"`
+	Baseline      string   `json:"baseline,omitempty"` // query selecting baseline executions
+	// Comparator overrides the comparator of the metric defined in the test, "LB" or "HB"
+	Comparator string `json:"comparator,omitempty"`
+}
+
+// Check is the outcome of a single condition of a rule
+type Check struct {
+	Test       string  `json:"test"`
+	Metric     string  `json:"metric"`
+	Parameters string  `json:"parameters,omitempty"`
+	Comparator string  `json:"comparator"` // "LB", "HB" or "Unknown"
+	Kind       string  `json:"kind"`       // "limit" or "regression"
+	Value      float64 `json:"value"`
+	Limit      float64 `json:"limit,omitempty"`
+	Baseline   float64 `json:"baseline,omitempty"`
+	Change     float64 `json:"change,omitempty"` // relative change against the baseline
+	Passed     bool    `json:"passed"`
+	Message    string  `json:"message"`
+}
+
+// Report holds results of all checks. Passed is false when any of the checks failed.
+type Report struct {
+	Passed bool    `json:"passed"`
+	Checks []Check `json:"checks"`
+}
+
+// Failures returns the failed checks
+func (r *Report) Failures() []Check {
+	var failed []Check
+	for _, c := range r.Checks {
+		if !c.Passed {
+			failed = append(failed, c)
+		}
+	}
+	return failed
+}
+
+// Load reads budgets in JSON format and validates them
+func Load(r io.Reader) (*File, error) {
+	var f File
+	decoder := json.NewDecoder(r)
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(&f); err != nil {
+		return nil, errors.Wrap(err, "Failed to parse budgets")
+	}
+	if err := f.Validate(); err != nil {
+		return nil, err
+	}
+	return &f, nil
+}
+
+// LoadFile reads budgets from the JSON file
+func LoadFile(path string) (*File, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, errors.Wrap(err, "Failed to open budgets")
+	}
+	defer file.Close()
+	return Load(file)
+}
+
+// Validate checks that the budgets are complete and their queries are well-formed
+func (f *File) Validate() error {
+	for i, b := range f.Budgets {
+		if b.Test == "" {
+			return errors.Errorf("Budget %d has no test", i)
+		}
+		if _, err := executionsSearch(b.Test, b.Executions); err != nil {
+			return errors.Wrap(err, fmt.Sprintf("Invalid executions of budget for test %s", b.Test))
+		}
+		for _, r := range b.Rules {
+			if r.Metric == "" {
+				return errors.Errorf("Rule of budget for test %s has no metric", b.Test)
+			}
+			if r.Limit == nil && r.MaxRegression == nil {
+				return errors.Errorf("Rule for metric %s of test %s has neither limit nor maxRegression",
+					r.Metric, b.Test)
+			}
+			if r.MaxRegression != nil && r.Baseline == "" {
+				return errors.Errorf("Rule for metric %s of test %s has maxRegression without baseline",
+					r.Metric, b.Test)
+			}
+			if _, err := parametersKey(r.Parameters); err != nil {
+				return errors.Wrap(err, fmt.Sprintf("Invalid parameters of metric %s of test %s", r.Metric, b.Test))
+			}
+			if r.Baseline != "" {
+				if _, err := search(b.Test, r.Baseline); err != nil {
+					return errors.Wrap(err, fmt.Sprintf("Invalid baseline of metric %s of test %s", r.Metric, b.Test))
+				}
+			}
+			if r.Comparator != "" {
+				c, err := apis.ParseComparator(r.Comparator)
+				if err == nil && c == apis.UnknownComparator {
+					err = errors.New("Comparator must be LB or HB")
+				}
+				if err != nil {
+					return errors.Wrap(err, fmt.Sprintf("Invalid comparator of metric %s of test %s", r.Metric, b.Test))
+				}
+			}
+		}
+	}
+	return nil
+}
+
+// Evaluate fetches the executions of each budget and checks its rules. A rule whose metric has no
+// values or an unknown comparator fails. Returns an error only when PerfRepo can't be queried.
+func Evaluate(c *client.PerfRepoClient, f *File) (*Report, error) {
+	if err := f.Validate(); err != nil {
+		return nil, err
+	}
+	report := &Report{Passed: true}
+	for _, b := range f.Budgets {
+		test, err := c.GetTestByUID(b.Test)
+		if err != nil {
+			return nil, errors.Wrap(err, fmt.Sprintf("Failed to evaluate budget for test %s", b.Test))
+		}
+		criteria, _ := executionsSearch(b.Test, b.Executions)
+		executions, err := c.SearchTestExecutions(criteria)
+		if err != nil {
+			return nil, errors.Wrap(err, fmt.Sprintf("Failed to fetch executions of test %s", b.Test))
+		}
+		for _, r := range b.Rules {
+			checks, err := evaluateRule(c, test, r, executions)
+			if err != nil {
+				return nil, err
+			}
+			for _, check := range checks {
+				report.Passed = report.Passed && check.Passed
+			}
+			report.Checks = append(report.Checks, checks...)
+		}
+	}
+	return report, nil
+}
+
+// unknownComparator explains checks failed because it's not known whether lower or higher values are better
+const unknownComparator = "unknown comparator of the metric, define the metric in the test or set comparator of the rule"
+
+func evaluateRule(c *client.PerfRepoClient, test *apis.Test, r Rule, executions []apis.TestExecution) ([]Check, error) {
+	cmp := comparator(test, r)
+	r.Parameters, _ = parametersKey(r.Parameters)
+	base := Check{Test: test.UID, Metric: r.Metric, Parameters: r.Parameters, Comparator: cmp.String()}
+	value, ok := aggregate(executions, r)
+	var checks []Check
+	if r.Limit != nil {
+		check := base
+		check.Kind = "limit"
+		check.Limit = *r.Limit
+		check.Value = value
+		switch {
+		case !ok:
+			check.Message = "no values of the metric"
+		case cmp == apis.UnknownComparator:
+			check.Message = unknownComparator
+		case cmp == apis.HBComparator:
+			check.Passed = value >= check.Limit
+			check.Message = fmt.Sprintf("%g >= %g", value, check.Limit)
+		default:
+			check.Passed = value <= check.Limit
+			check.Message = fmt.Sprintf("%g <= %g", value, check.Limit)
+		}
+		checks = append(checks, check)
+	}
+	if r.MaxRegression != nil {
+		check := base
+		check.Kind = "regression"
+		check.Limit = *r.MaxRegression
+		check.Value = value
+		criteria, _ := search(test.UID, r.Baseline)
+		baseline, err := c.SearchTestExecutions(criteria)
+		if err != nil {
+			return nil, errors.Wrap(err, fmt.Sprintf("Failed to fetch baseline of test %s", test.UID))
+		}
+		baseValue, baseOK := aggregate(baseline, r)
+		check.Baseline = baseValue
+		switch {
+		case !ok:
+			check.Message = "no values of the metric"
+		case !baseOK:
+			check.Message = "no baseline values of the metric"
+		case cmp == apis.UnknownComparator:
+			check.Message = unknownComparator
+		default:
+			check.Change = compare.RelativeChange(baseValue, value)
+			verdict := compare.Judge(cmp, value-baseValue, check.Change,
+				compare.Threshold{Relative: check.Limit})
+			check.Passed = verdict != compare.Regressed
+			check.Message = fmt.Sprintf("%s by %+.2f%% against %g, at most %.2f%% allowed",
+				verdict, 100*check.Change, baseValue, 100*check.Limit)
+		}
+		checks = append(checks, check)
+	}
+	return checks, nil
+}
+
+// parametersKey converts the parameters of a rule to the form of apis.Value.ParametersKey
+func parametersKey(parameters string) (string, error) {
+	if strings.TrimSpace(parameters) == "" {
+		return "", nil
+	}
+	parts := strings.Split(parameters, ",")
+	for i, p := range parts {
+		nameValue := strings.SplitN(p, "=", 2)
+		name := strings.TrimSpace(nameValue[0])
+		if len(nameValue) != 2 || name == "" {
+			return "", errors.Errorf("Parameter %q must be a name=value pair", strings.TrimSpace(p))
+		}
+		parts[i] = name + "=" + strings.TrimSpace(nameValue[1])
+	}
+	sort.Strings(parts)
+	return strings.Join(parts, ", "), nil
+}
+
+// aggregate returns the median of values of the rule metric in the executions
+func aggregate(executions []apis.TestExecution, r Rule) (float64, bool) {
+	var values []float64
+	for i := range executions {
+		for j := range executions[i].Values {
+			v := &executions[i].Values[j]
+			if v.MetricName == r.Metric && v.ParametersKey() == r.Parameters {
+				values = append(values, v.Result)
+			}
+		}
+	}
+	if len(values) == 0 {
+		return 0, false
+	}
+	return stats.Median(values), true
+}
+
+func comparator(test *apis.Test, r Rule) apis.Comparator {
+	if r.Comparator != "" {
+		c, _ := apis.ParseComparator(r.Comparator)
+		return c
+	}
+	for _, m := range test.Metrics {
+		if m.Name == r.Metric {
+			return m.Comparator
+		}
+	}
+	return apis.UnknownComparator
+}
+
+func executionsSearch(testUID, q string) (*apis.TestExecutionSearch, error) {
+	if q == "" {
+		q = DefaultExecutions
+	}
+	return search(testUID, q)
+}
+
+// search parses the query and restricts it to the test
+func search(testUID, q string) (*apis.TestExecutionSearch, error) {
+	criteria, err := query.Parse(q)
+	if err != nil {
+		return nil, err
+	}
+	criteria.TestUID = testUID
+	return criteria, nil
+}
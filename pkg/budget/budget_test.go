@@ -0,0 +1,122 @@
+package budget
+
+import (
+	"encoding/xml"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/mgencur/go-perfrepoclient/pkg/apis"
+	"github.com/mgencur/go-perfrepoclient/pkg/client"
+)
+
+const testXML = `<test id="1" name="service" groupId="perfrepouser" uid="service">
+<metrics><metric name="latency" comparator="LB"/><metric name="throughput" comparator="HB"/></metrics></test>`
+
+const nightlyXML = `<testExecutions>
+<testExecution id="2" name="nightly" testId="1" testUid="service" started="2016-07-07T00:00:00-00:00">
+<values><value metricName="latency" result="130"/><value metricName="throughput" result="990"/><value metricName="undefined" result="20"/>
+<value metricName="latency" result="80"><parameters><parameter name="threads" value="4"/><parameter name="client" value="2"/></parameters></value></values></testExecution>
+</testExecutions>`
+
+const releaseXML = `<testExecutions>
+<testExecution id="1" name="release" testId="1" testUid="service" started="2016-07-01T00:00:00-00:00">
+<values><value metricName="latency" result="100"/><value metricName="throughput" result="1000"/><value metricName="undefined" result="10"/></values></testExecution>
+</testExecutions>`
+
+const budgetsJSON = `{"budgets": [{"test": "service", "executions": "tag:nightly", "rules": [
+	{"metric": "latency", "limit": 120},
+	{"metric": "throughput", "limit": 900, "maxRegression": 0.03, "baseline": "tag:release"},
+	{"metric": "missing", "limit": 1},
+	{"metric": "undefined", "limit": 100, "maxRegression": 1.5, "baseline": "tag:release"},
+	{"metric": "latency", "parameters": "threads = 4,client=2", "limit": 100}
+]}]}`
+
+func TestEvaluate(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case r.URL.Path == "/rest/test/uid/service":
+			w.Write([]byte(testXML))
+		case r.URL.Path == "/rest/testExecution/search":
+			body, _ := ioutil.ReadAll(r.Body)
+			var criteria apis.TestExecutionSearch
+			if err := xml.Unmarshal(body, &criteria); err != nil || criteria.TestUID != "service" {
+				w.WriteHeader(http.StatusBadRequest)
+				return
+			}
+			if criteria.Tags == "release" {
+				w.Write([]byte(releaseXML))
+			} else {
+				w.Write([]byte(nightlyXML))
+			}
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer server.Close()
+
+	budgets, err := Load(strings.NewReader(budgetsJSON))
+	if err != nil {
+		t.Fatal("Failed to load budgets", err)
+	}
+	report, err := Evaluate(client.NewClient(server.URL, "user", "pass"), budgets)
+	if err != nil {
+		t.Fatal("Failed to evaluate budgets", err)
+	}
+
+	expected := []struct {
+		metric string
+		kind   string
+		passed bool
+	}{
+		{"latency", "limit", false},
+		{"throughput", "limit", true},
+		{"throughput", "regression", true},
+		{"missing", "limit", false},
+		{"undefined", "limit", false},
+		{"undefined", "regression", false},
+		{"latency", "limit", true},
+	}
+	if report.Passed || len(report.Checks) != len(expected) || len(report.Failures()) != 4 {
+		t.Fatalf("Unexpected report %+v", report)
+	}
+	for i, e := range expected {
+		c := report.Checks[i]
+		if c.Metric != e.metric || c.Kind != e.kind || c.Passed != e.passed {
+			t.Errorf("Expected %s %s passed=%v, got %+v", e.metric, e.kind, e.passed, c)
+		}
+	}
+	if c := report.Checks[2]; c.Baseline != 1000 || c.Change != -0.01 || c.Comparator != "HB" {
+		t.Errorf("Unexpected regression check %+v", c)
+	}
+	//without a comparator the limit and the regression can't be judged
+	for _, c := range report.Checks[4:6] {
+		if c.Comparator != "Unknown" || !strings.Contains(c.Message, "unknown comparator") {
+			t.Errorf("Expected failure for unknown comparator, got %+v", c)
+		}
+	}
+	//parameters are matched regardless of their order and spacing
+	if c := report.Checks[6]; c.Parameters != "client=2, threads=4" || c.Value != 80 {
+		t.Errorf("Unexpected check of multi-value metric %+v", c)
+	}
+}
+
+func TestLoadInvalid(t *testing.T) {
+	invalid := []string{
+		`{"budgets": [{"rules": []}]}`,
+		`{"budgets": [{"test": "t", "rules": [{"metric": "m"}]}]}`,
+		`{"budgets": [{"test": "t", "rules": [{"metric": "m", "maxRegression": 0.1}]}]}`,
+		`{"budgets": [{"test": "t", "executions": "limit:x", "rules": []}]}`,
+		`{"budgets": [{"test": "t", "rules": [{"metric": "m", "limit": 1, "comparator": "XY"}]}]}`,
+		`{"budgets": [{"test": "t", "rules": [{"metric": "m", "limit": 1, "comparator": "Unknown"}]}]}`,
+		`{"budgets": [{"test": "t", "unknown": 1}]}`,
+		`{"budgets": [{"test": "t", "rules": [{"metric": "m", "limit": 1, "parameters": "client=2, threads"}]}]}`,
+	}
+	for _, b := range invalid {
+		if _, err := Load(strings.NewReader(b)); err == nil {
+			t.Errorf("Expected an error for %s", b)
+		}
+	}
+}
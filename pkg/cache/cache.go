@@ -0,0 +1,324 @@
+// Package cache provides a PerfRepo client which keeps tests, test executions and search
+// results in a local directory so that repeated runs don't download them again.
+package cache
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/xml"
+	"fmt"
+	"io/ioutil"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strconv"
+	"time"
+
+	"github.com/pkg/errors"
+
+	"github.com/mgencur/go-perfrepoclient/pkg/apis"
+	"github.com/mgencur/go-perfrepoclient/pkg/client"
+)
+
+const (
+	testsDir      = "tests"
+	testUIDsDir   = "test-uids"
+	executionsDir = "executions"
+	searchesDir   = "searches"
+)
+
+// Options configures the cache. A zero TTL means entries never expire.
+type Options struct {
+	TestTTL      time.Duration // expiration of tests which may get new metrics
+	ExecutionTTL time.Duration // expiration of test executions, usually immutable
+	SearchTTL    time.Duration // expiration of search results which change with new executions
+	// Offline serves only cached entries, including expired ones, and never contacts PerfRepo.
+	// Operations modifying data fail with *OfflineError in offline mode.
+	Offline bool
+}
+
+// NotCachedError is returned in offline mode when the requested entity isn't cached
+type NotCachedError struct {
+	Key string
+}
+
+func (e *NotCachedError) Error() string {
+	return fmt.Sprintf("Entity %s is not cached", e.Key)
+}
+
+// OfflineError is returned in offline mode by operations which need PerfRepo
+type OfflineError struct {
+	Operation string
+}
+
+func (e *OfflineError) Error() string {
+	return fmt.Sprintf("Operation %s not supported in offline mode", e.Operation)
+}
+
+// Client caches results of GetTest, GetTestByUID, GetTestExecution and SearchTestExecutions on disk.
+// Updates and deletes made through it invalidate affected entries. Operations the client doesn't
+// cache are available through Uncached so that they can't bypass the cache by accident.
+type Client struct {
+	client *client.PerfRepoClient
+	dir    string
+	opts   Options
+}
+
+// NewClient creates a caching client storing entries in dir. The wrapped client may be nil in
+// offline mode.
+func NewClient(c *client.PerfRepoClient, dir string, opts Options) (*Client, error) {
+	if c == nil && !opts.Offline {
+		return nil, errors.New("PerfRepo client is required unless offline")
+	}
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return nil, errors.Wrap(err, "Unable to create cache directory")
+	}
+	return &Client{client: c, dir: dir, opts: opts}, nil
+}
+
+// GetTest returns the test by its identifier from cache or from PerfRepo
+func (c *Client) GetTest(id int64) (*apis.Test, error) {
+	path := c.testPath(id)
+	var test apis.Test
+	if c.load(path, c.opts.TestTTL, &test) {
+		return &test, nil
+	}
+	if c.opts.Offline {
+		return nil, &NotCachedError{Key: path}
+	}
+	fetched, err := c.client.GetTest(id)
+	if err != nil {
+		return nil, err
+	}
+	c.storeTest(fetched)
+	return fetched, nil
+}
+
+// GetTestByUID returns the test by its UID from cache or from PerfRepo
+func (c *Client) GetTestByUID(uid string) (*apis.Test, error) {
+	path := c.testUIDPath(uid)
+	var test apis.Test
+	if c.load(path, c.opts.TestTTL, &test) {
+		return &test, nil
+	}
+	if c.opts.Offline {
+		return nil, &NotCachedError{Key: path}
+	}
+	fetched, err := c.client.GetTestByUID(uid)
+	if err != nil {
+		return nil, err
+	}
+	c.storeTest(fetched)
+	return fetched, nil
+}
+
+// GetTestExecution returns the test execution by its identifier from cache or from PerfRepo
+func (c *Client) GetTestExecution(id int64) (*apis.TestExecution, error) {
+	path := c.executionPath(id)
+	var exec apis.TestExecution
+	if c.load(path, c.opts.ExecutionTTL, &exec) {
+		return &exec, nil
+	}
+	if c.opts.Offline {
+		return nil, &NotCachedError{Key: path}
+	}
+	fetched, err := c.client.GetTestExecution(id)
+	if err != nil {
+		return nil, err
+	}
+	c.store(path, fetched)
+	return fetched, nil
+}
+
+// SearchTestExecutions returns results of the search from cache or from PerfRepo. Results are
+// cached per criteria.
+func (c *Client) SearchTestExecutions(criteria *apis.TestExecutionSearch) ([]apis.TestExecution, error) {
+	path, err := c.searchPath(criteria)
+	if err != nil {
+		return nil, err
+	}
+	var results apis.TestExecutions
+	if c.load(path, c.opts.SearchTTL, &results) {
+		return results.TestExecutions, nil
+	}
+	if c.opts.Offline {
+		return nil, &NotCachedError{Key: path}
+	}
+	fetched, err := c.client.SearchTestExecutions(criteria)
+	if err != nil {
+		return nil, err
+	}
+	c.store(path, &apis.TestExecutions{TestExecutions: fetched})
+	return fetched, nil
+}
+
+// AddMetric adds a metric to the test in PerfRepo and invalidates the cached test
+func (c *Client) AddMetric(testID int64, metric *apis.Metric) (int64, error) {
+	if err := c.checkOnline("AddMetric"); err != nil {
+		return 0, err
+	}
+	c.invalidateTest(testID)
+	return c.client.AddMetric(testID, metric)
+}
+
+// DeleteTest deletes the test from PerfRepo and invalidates the cached test and searches
+func (c *Client) DeleteTest(id int64) error {
+	if err := c.checkOnline("DeleteTest"); err != nil {
+		return err
+	}
+	c.invalidateTest(id)
+	c.invalidateSearches()
+	return c.client.DeleteTest(id)
+}
+
+// CreateTestExecution creates the test execution in PerfRepo and invalidates cached searches
+func (c *Client) CreateTestExecution(testExec *apis.TestExecution) (int64, error) {
+	if err := c.checkOnline("CreateTestExecution"); err != nil {
+		return 0, err
+	}
+	c.invalidateSearches()
+	return c.client.CreateTestExecution(testExec)
+}
+
+// UpdateTestExecution updates the test execution in PerfRepo and invalidates the cached
+// execution and searches
+func (c *Client) UpdateTestExecution(testExec *apis.TestExecution) (int64, error) {
+	if err := c.checkOnline("UpdateTestExecution"); err != nil {
+		return 0, err
+	}
+	if testExec != nil {
+		c.remove(c.executionPath(testExec.ID))
+	}
+	c.invalidateSearches()
+	return c.client.UpdateTestExecution(testExec)
+}
+
+// DeleteTestExecution deletes the test execution from PerfRepo and invalidates the cached
+// execution and searches
+func (c *Client) DeleteTestExecution(id int64) error {
+	if err := c.checkOnline("DeleteTestExecution"); err != nil {
+		return err
+	}
+	c.remove(c.executionPath(id))
+	c.invalidateSearches()
+	return c.client.DeleteTestExecution(id)
+}
+
+// CreateTest creates the test in PerfRepo, there's nothing cached to invalidate
+func (c *Client) CreateTest(test *apis.Test) (int64, error) {
+	if err := c.checkOnline("CreateTest"); err != nil {
+		return 0, err
+	}
+	return c.client.CreateTest(test)
+}
+
+// Uncached returns the wrapped client for operations which aren't cached. Changes made through it
+// don't invalidate cached entries. Returns an *OfflineError in offline mode.
+func (c *Client) Uncached() (*client.PerfRepoClient, error) {
+	if err := c.checkOnline("Uncached"); err != nil {
+		return nil, err
+	}
+	return c.client, nil
+}
+
+// Purge removes all cached entries
+func (c *Client) Purge() error {
+	for _, d := range []string{testsDir, testUIDsDir, executionsDir, searchesDir} {
+		if err := os.RemoveAll(filepath.Join(c.dir, d)); err != nil {
+			return errors.Wrap(err, "Failed to purge cache")
+		}
+	}
+	return nil
+}
+
+func (c *Client) checkOnline(operation string) error {
+	if c.opts.Offline || c.client == nil {
+		return &OfflineError{Operation: operation}
+	}
+	return nil
+}
+
+func (c *Client) testPath(id int64) string {
+	return filepath.Join(c.dir, testsDir, strconv.FormatInt(id, 10)+".xml")
+}
+
+func (c *Client) testUIDPath(uid string) string {
+	return filepath.Join(c.dir, testUIDsDir, url.PathEscape(uid)+".xml")
+}
+
+func (c *Client) executionPath(id int64) string {
+	return filepath.Join(c.dir, executionsDir, strconv.FormatInt(id, 10)+".xml")
+}
+
+func (c *Client) searchPath(criteria *apis.TestExecutionSearch) (string, error) {
+	marshalled, err := xml.Marshal(criteria)
+	if err != nil {
+		return "", errors.Wrap(err, "Invalid search criteria")
+	}
+	sum := sha256.Sum256(marshalled)
+	return filepath.Join(c.dir, searchesDir, hex.EncodeToString(sum[:])+".xml"), nil
+}
+
+func (c *Client) storeTest(test *apis.Test) {
+	c.store(c.testPath(test.ID), test)
+	c.store(c.testUIDPath(test.UID), test)
+}
+
+func (c *Client) invalidateTest(id int64) {
+	var test apis.Test
+	path := c.testPath(id)
+	if c.load(path, 0, &test) {
+		c.remove(c.testUIDPath(test.UID))
+	}
+	c.remove(path)
+}
+
+func (c *Client) invalidateSearches() {
+	os.RemoveAll(filepath.Join(c.dir, searchesDir))
+}
+
+// load reads a cached entry into v. Returns false when the entry is missing, expired or unreadable.
+func (c *Client) load(path string, ttl time.Duration, v interface{}) bool {
+	info, err := os.Stat(path)
+	if err != nil {
+		return false
+	}
+	if !c.opts.Offline && ttl > 0 && time.Since(info.ModTime()) > ttl {
+		return false
+	}
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return false
+	}
+	return xml.Unmarshal(data, v) == nil
+}
+
+// store writes the entry atomically. Failures are ignored, the entry is fetched again next time.
+func (c *Client) store(path string, v interface{}) {
+	data, err := xml.Marshal(v)
+	if err != nil {
+		return
+	}
+	dir := filepath.Dir(path)
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return
+	}
+	tmp, err := ioutil.TempFile(dir, ".tmp-")
+	if err != nil {
+		return
+	}
+	_, err = tmp.Write(data)
+	if closeErr := tmp.Close(); err == nil {
+		err = closeErr
+	}
+	if err == nil {
+		err = os.Rename(tmp.Name(), path)
+	}
+	if err != nil {
+		os.Remove(tmp.Name())
+	}
+}
+
+func (c *Client) remove(path string) {
+	os.Remove(path)
+}
@@ -0,0 +1,205 @@
+package cache
+
+import (
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/mgencur/go-perfrepoclient/pkg/apis"
+	"github.com/mgencur/go-perfrepoclient/pkg/client"
+)
+
+const executionXML = `<testExecution id="1" name="execution1" testId="2" testUid="test2" started="2016-07-07T00:00:00-00:00">
+<values><value metricName="metric1" result="12"/></values></testExecution>`
+
+const testXML = `<test id="2" name="test2" groupId="perfrepouser" uid="test2">
+<metrics><metric name="metric1" comparator="LB"/></metrics></test>`
+
+const searchXML = `<testExecutions>` + executionXML + `</testExecutions>`
+
+func TestCachedTestExecution(t *testing.T) {
+	requests := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		switch {
+		case r.Method == http.MethodGet && r.URL.Path == "/rest/testExecution/1":
+			w.Write([]byte(executionXML))
+		case r.Method == http.MethodDelete && r.URL.Path == "/rest/testExecution/1":
+			w.WriteHeader(http.StatusNoContent)
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer server.Close()
+
+	dir, err := ioutil.TempDir("", "perfrepo-cache")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	c, err := NewClient(client.NewClient(server.URL, "user", "pass"), dir, Options{})
+	if err != nil {
+		t.Fatal("Failed to create cache", err)
+	}
+
+	for i := 0; i < 2; i++ {
+		exec, err := c.GetTestExecution(1)
+		if err != nil {
+			t.Fatal("Failed to get TestExecution", err)
+		}
+		if exec.ID != 1 || exec.Name != "execution1" || len(exec.Values) != 1 || exec.Values[0].Result != 12 {
+			t.Fatalf("Unexpected test execution %+v", exec)
+		}
+	}
+	if requests != 1 {
+		t.Fatalf("Expected 1 request, got %d", requests)
+	}
+
+	offline, err := NewClient(nil, dir, Options{Offline: true})
+	if err != nil {
+		t.Fatal("Failed to create offline cache", err)
+	}
+	if _, err := offline.GetTestExecution(1); err != nil {
+		t.Fatal("Cached TestExecution not available offline", err)
+	}
+	if _, err := offline.GetTestExecution(3); err == nil {
+		t.Fatal("Uncached TestExecution available offline")
+	}
+	if err := offline.DeleteTestExecution(1); err == nil {
+		t.Fatal("Delete succeeded offline")
+	}
+
+	if err := c.DeleteTestExecution(1); err != nil {
+		t.Fatal("Failed to delete TestExecution", err)
+	}
+	if _, err := offline.GetTestExecution(1); err == nil {
+		t.Fatal("Deleted TestExecution still cached")
+	}
+
+	expiring, err := NewClient(client.NewClient(server.URL, "user", "pass"), dir, Options{ExecutionTTL: time.Nanosecond})
+	if err != nil {
+		t.Fatal("Failed to create cache", err)
+	}
+	requests = 0
+	for i := 0; i < 2; i++ {
+		if _, err := expiring.GetTestExecution(1); err != nil {
+			t.Fatal("Failed to get TestExecution", err)
+		}
+		time.Sleep(time.Millisecond)
+	}
+	if requests != 2 {
+		t.Fatalf("Expired entry not fetched again, got %d requests", requests)
+	}
+}
+
+func TestOfflineAndInvalidation(t *testing.T) {
+	requests := make(map[string]int)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests[r.Method+" "+r.URL.Path]++
+		switch r.Method + " " + r.URL.Path {
+		case "GET /rest/test/id/2", "GET /rest/test/uid/test2":
+			w.Write([]byte(testXML))
+		case "GET /rest/testExecution/1":
+			w.Write([]byte(executionXML))
+		case "POST /rest/testExecution/search":
+			w.Write([]byte(searchXML))
+		case "POST /rest/testExecution/update/1":
+			w.WriteHeader(http.StatusCreated)
+			w.Write([]byte("1"))
+		case "DELETE /rest/testExecution/1":
+			w.WriteHeader(http.StatusNoContent)
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer server.Close()
+
+	dir, err := ioutil.TempDir("", "perfrepo-cache")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	online, err := NewClient(client.NewClient(server.URL, "user", "pass"), dir, Options{})
+	if err != nil {
+		t.Fatal("Failed to create cache", err)
+	}
+	criteria := &apis.TestExecutionSearch{TestUID: "test2"}
+	if _, err := online.GetTest(2); err != nil {
+		t.Fatal("Failed to get Test", err)
+	}
+	if _, err := online.GetTestExecution(1); err != nil {
+		t.Fatal("Failed to get TestExecution", err)
+	}
+	if _, err := online.SearchTestExecutions(criteria); err != nil {
+		t.Fatal("Failed to search TestExecutions", err)
+	}
+
+	offline, err := NewClient(nil, dir, Options{Offline: true})
+	if err != nil {
+		t.Fatal("Failed to create offline cache", err)
+	}
+	//the test was fetched by ID and is cached by UID as well
+	if test, err := offline.GetTestByUID("test2"); err != nil || test.ID != 2 {
+		t.Errorf("Cached Test not available offline: %+v, %v", test, err)
+	}
+	if execs, err := offline.SearchTestExecutions(criteria); err != nil || len(execs) != 1 {
+		t.Errorf("Cached search not available offline: %+v, %v", execs, err)
+	}
+	if _, err := offline.GetTest(3); !isNotCached(err) {
+		t.Errorf("Expected NotCachedError for uncached Test, got %v", err)
+	}
+	if _, err := offline.SearchTestExecutions(&apis.TestExecutionSearch{TestUID: "other"}); !isNotCached(err) {
+		t.Errorf("Expected NotCachedError for uncached search, got %v", err)
+	}
+	if _, err := offline.CreateTest(&apis.Test{}); !isOffline(err) {
+		t.Errorf("Expected OfflineError for CreateTest, got %v", err)
+	}
+	if _, err := offline.Uncached(); !isOffline(err) {
+		t.Errorf("Expected OfflineError for Uncached, got %v", err)
+	}
+	if _, err := offline.UpdateTestExecution(&apis.TestExecution{ID: 1}); !isOffline(err) {
+		t.Errorf("Expected OfflineError for UpdateTestExecution, got %v", err)
+	}
+
+	exec, err := online.GetTestExecution(1)
+	if err != nil {
+		t.Fatal("Failed to get TestExecution", err)
+	}
+	if _, err := online.UpdateTestExecution(exec); err != nil {
+		t.Fatal("Failed to update TestExecution", err)
+	}
+	if _, err := offline.GetTestExecution(1); !isNotCached(err) {
+		t.Errorf("Updated TestExecution still cached: %v", err)
+	}
+	if _, err := offline.SearchTestExecutions(criteria); !isNotCached(err) {
+		t.Errorf("Search still cached after update: %v", err)
+	}
+
+	if _, err := online.SearchTestExecutions(criteria); err != nil {
+		t.Fatal("Failed to search TestExecutions", err)
+	}
+	if err := online.DeleteTestExecution(1); err != nil {
+		t.Fatal("Failed to delete TestExecution", err)
+	}
+	if _, err := offline.SearchTestExecutions(criteria); !isNotCached(err) {
+		t.Errorf("Search still cached after delete: %v", err)
+	}
+	if requests["POST /rest/testExecution/search"] != 2 {
+		t.Errorf("Expected 2 searches, got %v", requests)
+	}
+}
+
+func isNotCached(err error) bool {
+	_, ok := err.(*NotCachedError)
+	return ok
+}
+
+func isOffline(err error) bool {
+	_, ok := err.(*OfflineError)
+	return ok
+}
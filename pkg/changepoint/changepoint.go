@@ -0,0 +1,326 @@
+// Package changepoint finds executions in the history of a metric after which its values shifted.
+package changepoint
+
+import (
+	"math"
+	"math/rand"
+	"sort"
+
+	"github.com/pkg/errors"
+
+	"github.com/mgencur/go-perfrepoclient/pkg/apis"
+	"github.com/mgencur/go-perfrepoclient/pkg/client"
+	"github.com/mgencur/go-perfrepoclient/pkg/compare"
+	"github.com/mgencur/go-perfrepoclient/pkg/stats"
+)
+
+const (
+	defaultAlpha        = 0.05
+	defaultPermutations = 199
+	defaultMinSegment   = 3
+)
+
+// DefaultKeyParameters are the execution parameters reported for executions around a change point
+// when Options.KeyParameters is empty
+var DefaultKeyParameters = []string{"git_commit", "commit", "revision"}
+
+// Method is the algorithm used to locate change points
+type Method int
+
+// enumerate values for Method
+const (
+	EDivisive Method = iota
+	CUSUM
+)
+
+// Options configures the detection. Zero values select defaults.
+type Options struct {
+	Method       Method
+	Alpha        float64 // significance level of a change point, 0.05 by default
+	Permutations int     // number of permutations of the significance test, 199 by default
+	MinSegment   int     // minimal number of points between change points, 3 by default
+	Seed         int64   // seed of the permutations so that results are reproducible
+	// Execution parameters identifying the build, e.g. git_commit, see DefaultKeyParameters
+	KeyParameters []string
+	// Comparator of the metric, the comparator of the values is used when unknown
+	Comparator apis.Comparator
+}
+
+// Point is a single value in the history of a metric
+type Point struct {
+	Execution *apis.TestExecution
+	Result    float64
+}
+
+// ChangePoint describes a shift of the metric values between two consecutive executions
+type ChangePoint struct {
+	Index      int                 // index of the first point after the change
+	Before     *apis.TestExecution // last execution before the change
+	After      *apis.TestExecution // first execution after the change
+	BeforeKeys []apis.TestExecutionParameter
+	AfterKeys  []apis.TestExecutionParameter
+	MeanBefore float64 // mean of the segment preceding the change
+	MeanAfter  float64 // mean of the segment following the change
+	Difference float64 // MeanAfter - MeanBefore
+	Change     float64 // relative change of the mean
+	PValue     float64 // p-value of the permutation test
+	Confidence float64 // 1 - PValue
+	Verdict    compare.Verdict
+}
+
+// History holds the change points found in the history of a metric sharing the same value parameters
+type History struct {
+	Metric       string
+	Parameters   string // value parameters, see apis.Value.ParametersKey
+	Points       []Point
+	ChangePoints []ChangePoint
+}
+
+// DetectHistory searches executions matching the criteria and detects change points in the history
+// of the metric. Multi-value metrics yield a history for each combination of value parameters.
+func DetectHistory(c *client.PerfRepoClient, criteria *apis.TestExecutionSearch, metric string,
+	opts Options) ([]History, error) {
+	executions, err := c.SearchTestExecutions(criteria)
+	if err != nil {
+		return nil, errors.Wrap(err, "Failed to fetch metric history")
+	}
+	return Detect(executions, metric, opts), nil
+}
+
+// Detect detects change points in the history of the metric built from the executions ordered
+// by their start. Executions without start time are ignored.
+func Detect(executions []apis.TestExecution, metric string, opts Options) []History {
+	opts.setDefaults()
+	comparators := make(map[string]apis.Comparator)
+	histories := make(map[string]*History)
+	for _, exec := range sortedByStart(executions) {
+		for j := range exec.Values {
+			v := &exec.Values[j]
+			if v.MetricName != metric {
+				continue
+			}
+			key := v.ParametersKey()
+			h, ok := histories[key]
+			if !ok {
+				h = &History{Metric: metric, Parameters: key}
+				histories[key] = h
+			}
+			if comparators[key] == apis.UnknownComparator {
+				comparators[key] = v.MetricComparator
+			}
+			h.Points = append(h.Points, Point{Execution: exec, Result: v.Result})
+		}
+	}
+
+	var result []History
+	for key, h := range histories {
+		comparator := opts.Comparator
+		if comparator == apis.UnknownComparator {
+			comparator = comparators[key]
+		}
+		h.ChangePoints = Points(h.Points, comparator, opts)
+		result = append(result, *h)
+	}
+	sort.Slice(result, func(i, j int) bool {
+		return result[i].Parameters < result[j].Parameters
+	})
+	return result
+}
+
+// Points detects change points in the ordered points by binary segmentation. The most significant
+// split among the current segments is accepted while its permutation test p-value is below Alpha.
+// Change points are ordered by index.
+func Points(points []Point, comparator apis.Comparator, opts Options) []ChangePoint {
+	opts.setDefaults()
+	values := make([]float64, len(points))
+	for i, p := range points {
+		values[i] = p.Result
+	}
+	statistic := eDivisive
+	if opts.Method == CUSUM {
+		statistic = cusum
+	}
+	rng := rand.New(rand.NewSource(opts.Seed))
+
+	type split struct {
+		index  int
+		pValue float64
+	}
+	var splits []split
+	bounds := []int{0, len(values)}
+	for {
+		best, bestStat, bestSegment := -1, 0.0, 0
+		for s := 0; s+1 < len(bounds); s++ {
+			tau, stat := statistic(values[bounds[s]:bounds[s+1]], opts.MinSegment)
+			if tau >= 0 && stat > bestStat {
+				best, bestStat, bestSegment = bounds[s]+tau, stat, s
+			}
+		}
+		if best < 0 {
+			break
+		}
+		segment := values[bounds[bestSegment]:bounds[bestSegment+1]]
+		p := permutationPValue(segment, bestStat, statistic, opts, rng)
+		if p >= opts.Alpha {
+			break
+		}
+		splits = append(splits, split{index: best, pValue: p})
+		bounds = append(bounds, best)
+		sort.Ints(bounds)
+	}
+
+	sort.Slice(splits, func(i, j int) bool {
+		return splits[i].index < splits[j].index
+	})
+	changePoints := make([]ChangePoint, 0, len(splits))
+	for i, s := range splits {
+		start, end := 0, len(values)
+		if i > 0 {
+			start = splits[i-1].index
+		}
+		if i+1 < len(splits) {
+			end = splits[i+1].index
+		}
+		cp := ChangePoint{
+			Index:      s.index,
+			Before:     points[s.index-1].Execution,
+			After:      points[s.index].Execution,
+			MeanBefore: stats.Mean(values[start:s.index]),
+			MeanAfter:  stats.Mean(values[s.index:end]),
+			PValue:     s.pValue,
+			Confidence: 1 - s.pValue,
+		}
+		cp.BeforeKeys = keyParameters(cp.Before, opts.KeyParameters)
+		cp.AfterKeys = keyParameters(cp.After, opts.KeyParameters)
+		cp.Difference = cp.MeanAfter - cp.MeanBefore
+		cp.Change = compare.RelativeChange(cp.MeanBefore, cp.MeanAfter)
+		cp.Verdict = compare.Judge(comparator, cp.Difference, cp.Change, compare.Threshold{})
+		changePoints = append(changePoints, cp)
+	}
+	return changePoints
+}
+
+// statisticFunc returns the best split of the values into [0, tau) and [tau, len) with both
+// parts having at least minSegment values, and its statistic. Returns -1 when there is none.
+type statisticFunc func(values []float64, minSegment int) (tau int, stat float64)
+
+// eDivisive returns the split maximizing the energy distance between both parts
+// (E-Divisive with alpha = 1)
+func eDivisive(values []float64, minSegment int) (int, float64) {
+	n := len(values)
+	if n < 2*minSegment {
+		return -1, 0
+	}
+	dist := func(i, j int) float64 {
+		return math.Abs(values[i] - values[j])
+	}
+	//within sums of pairs in [0, tau) and [tau, n) and the cross sum, updated as tau grows
+	within, rest, cross := 0.0, 0.0, 0.0
+	for i := 0; i < n; i++ {
+		for j := i + 1; j < n; j++ {
+			rest += dist(i, j)
+		}
+	}
+	best, bestStat := -1, 0.0
+	for tau := 1; tau <= n-minSegment; tau++ {
+		moved := tau - 1
+		for i := 0; i < moved; i++ {
+			within += dist(i, moved)
+			cross -= dist(i, moved)
+		}
+		for j := moved + 1; j < n; j++ {
+			rest -= dist(moved, j)
+			cross += dist(moved, j)
+		}
+		if tau < minSegment {
+			continue
+		}
+		m, k := float64(tau), float64(n-tau)
+		stat := m * k / (m + k) * (2*cross/(m*k) - pairMean(within, m) - pairMean(rest, k))
+		if best < 0 || stat > bestStat {
+			best, bestStat = tau, stat
+		}
+	}
+	return best, bestStat
+}
+
+// pairMean returns the mean distance given the sum of distances over all pairs of n values
+func pairMean(sum, n float64) float64 {
+	if n < 2 {
+		return 0
+	}
+	return sum / (n * (n - 1) / 2)
+}
+
+// cusum returns the split maximizing the absolute cumulative sum of deviations from the mean
+func cusum(values []float64, minSegment int) (int, float64) {
+	n := len(values)
+	if n < 2*minSegment {
+		return -1, 0
+	}
+	mean := stats.Mean(values)
+	best, bestStat, sum := -1, 0.0, 0.0
+	for tau := 1; tau <= n-minSegment; tau++ {
+		sum += values[tau-1] - mean
+		if tau >= minSegment && (best < 0 || math.Abs(sum) > bestStat) {
+			best, bestStat = tau, math.Abs(sum)
+		}
+	}
+	return best, bestStat
+}
+
+// permutationPValue estimates the probability that a random order of the values yields
+// a statistic at least as large as the observed one
+func permutationPValue(values []float64, observed float64, statistic statisticFunc, opts Options,
+	rng *rand.Rand) float64 {
+	shuffled := append([]float64(nil), values...)
+	exceeded := 0
+	for i := 0; i < opts.Permutations; i++ {
+		rng.Shuffle(len(shuffled), func(a, b int) {
+			shuffled[a], shuffled[b] = shuffled[b], shuffled[a]
+		})
+		if _, stat := statistic(shuffled, opts.MinSegment); stat >= observed {
+			exceeded++
+		}
+	}
+	return float64(exceeded+1) / float64(opts.Permutations+1)
+}
+
+func keyParameters(exec *apis.TestExecution, names []string) []apis.TestExecutionParameter {
+	params := exec.ParametersMap()
+	var keys []apis.TestExecutionParameter
+	for _, name := range names {
+		if value, ok := params[name]; ok {
+			keys = append(keys, apis.TestExecutionParameter{Name: name, Value: value})
+		}
+	}
+	return keys
+}
+
+func sortedByStart(executions []apis.TestExecution) []*apis.TestExecution {
+	sorted := make([]*apis.TestExecution, 0, len(executions))
+	for i := range executions {
+		if executions[i].Started != nil {
+			sorted = append(sorted, &executions[i])
+		}
+	}
+	sort.SliceStable(sorted, func(i, j int) bool {
+		return sorted[i].Started.Before(sorted[j].Started.Time)
+	})
+	return sorted
+}
+
+func (o *Options) setDefaults() {
+	if o.Alpha <= 0 {
+		o.Alpha = defaultAlpha
+	}
+	if o.Permutations <= 0 {
+		o.Permutations = defaultPermutations
+	}
+	if o.MinSegment <= 0 {
+		o.MinSegment = defaultMinSegment
+	}
+	if len(o.KeyParameters) == 0 {
+		o.KeyParameters = DefaultKeyParameters
+	}
+}
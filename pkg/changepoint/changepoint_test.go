@@ -0,0 +1,64 @@
+package changepoint
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/mgencur/go-perfrepoclient/pkg/apis"
+	"github.com/mgencur/go-perfrepoclient/pkg/compare"
+)
+
+// history returns executions with one value of metric "latency" each, started an hour apart
+// in reverse order to check sorting
+func history(results ...float64) []apis.TestExecution {
+	start := time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC)
+	executions := make([]apis.TestExecution, len(results))
+	for i, r := range results {
+		executions[len(results)-1-i] = apis.TestExecution{
+			ID:         int64(i + 1),
+			Started:    &apis.JaxbTime{Time: start.Add(time.Duration(i) * time.Hour)},
+			Parameters: []apis.TestExecutionParameter{{Name: "git_commit", Value: fmt.Sprintf("c%d", i)}},
+			Values: []apis.Value{{
+				MetricName:       "latency",
+				MetricComparator: apis.LBComparator,
+				Result:           r,
+			}},
+		}
+	}
+	return executions
+}
+
+func TestDetectStep(t *testing.T) {
+	executions := history(100, 101, 99, 100, 102, 100, 99, 101, 120, 121, 119, 120, 122, 120, 121, 119)
+	for _, method := range []Method{EDivisive, CUSUM} {
+		histories := Detect(executions, "latency", Options{Method: method})
+		if len(histories) != 1 {
+			t.Fatalf("Expected a single history, got %+v", histories)
+		}
+		cps := histories[0].ChangePoints
+		if len(cps) != 1 {
+			t.Fatalf("Method %d: expected a single change point, got %+v", method, cps)
+		}
+		cp := cps[0]
+		if cp.Index != 8 || cp.Before.ID != 8 || cp.After.ID != 9 {
+			t.Errorf("Method %d: unexpected change point %+v", method, cp)
+		}
+		if len(cp.AfterKeys) != 1 || cp.AfterKeys[0].Value != "c8" {
+			t.Errorf("Method %d: unexpected key parameters %+v", method, cp.AfterKeys)
+		}
+		if cp.Verdict != compare.Regressed || cp.Difference < 19 || cp.Confidence < 0.95 {
+			t.Errorf("Method %d: unexpected magnitude %+v", method, cp)
+		}
+	}
+}
+
+func TestDetectStable(t *testing.T) {
+	executions := history(100, 101, 99, 100, 102, 100, 99, 101, 100, 101, 99, 100)
+	for _, method := range []Method{EDivisive, CUSUM} {
+		histories := Detect(executions, "latency", Options{Method: method})
+		if len(histories) != 1 || len(histories[0].ChangePoints) != 0 {
+			t.Errorf("Method %d: expected no change points, got %+v", method, histories)
+		}
+	}
+}
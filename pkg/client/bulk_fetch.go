@@ -0,0 +1,104 @@
+package client
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/mgencur/go-perfrepoclient/pkg/apis"
+)
+
+const defaultConcurrency = 8
+
+// ExecutionResult holds the outcome of fetching a single test execution
+type ExecutionResult struct {
+	ID        int64
+	Execution *apis.TestExecution // nil when Err is set
+	Err       error
+}
+
+// BulkFetchError aggregates failures of GetTestExecutions
+type BulkFetchError struct {
+	NotFound []int64         // IDs of executions that don't exist
+	Failed   map[int64]error // IDs of executions that couldn't be fetched for other reasons
+}
+
+func (e *BulkFetchError) Error() string {
+	var parts []string
+	if len(e.NotFound) > 0 {
+		parts = append(parts, fmt.Sprintf("%d test executions not found %v", len(e.NotFound), e.NotFound))
+	}
+	if len(e.Failed) > 0 {
+		ids := make([]int64, 0, len(e.Failed))
+		for id := range e.Failed {
+			ids = append(ids, id)
+		}
+		sort.Slice(ids, func(i, j int) bool { return ids[i] < ids[j] })
+		parts = append(parts, fmt.Sprintf("%d test executions failed, e.g. %d: %v", len(ids), ids[0], e.Failed[ids[0]]))
+	}
+	return "Failed to fetch test executions: " + strings.Join(parts, "; ")
+}
+
+// GetTestExecutions fetches test executions by their IDs using at most concurrency parallel
+// requests (8 when not positive). Repeated IDs are fetched once. Returns a result for each
+// distinct ID in the order of first occurrence. Failures of individual executions don't stop
+// the others, they are reported in the results and aggregated in a *BulkFetchError.
+func (c *PerfRepoClient) GetTestExecutions(ctx context.Context, ids []int64, concurrency int) ([]ExecutionResult, error) {
+	var results []ExecutionResult
+	seen := make(map[int64]bool)
+	for _, id := range ids {
+		if !seen[id] {
+			seen[id] = true
+			results = append(results, ExecutionResult{ID: id})
+		}
+	}
+	if concurrency <= 0 {
+		concurrency = defaultConcurrency
+	}
+
+	indexes := make(chan int)
+	var wg sync.WaitGroup
+	for w := 0; w < concurrency && w < len(results); w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range indexes {
+				r := &results[i]
+				if err := ctx.Err(); err != nil {
+					r.Err = err
+					continue
+				}
+				r.Execution, r.Err = c.getTestExecution(ctx, r.ID)
+				if r.Err != nil {
+					r.Execution = nil
+				}
+			}
+		}()
+	}
+	for i := range results {
+		indexes <- i
+	}
+	close(indexes)
+	wg.Wait()
+
+	var bulkErr *BulkFetchError
+	for _, r := range results {
+		if r.Err == nil {
+			continue
+		}
+		if bulkErr == nil {
+			bulkErr = &BulkFetchError{Failed: make(map[int64]error)}
+		}
+		if IsNotFound(r.Err) {
+			bulkErr.NotFound = append(bulkErr.NotFound, r.ID)
+		} else {
+			bulkErr.Failed[r.ID] = r.Err
+		}
+	}
+	if bulkErr != nil {
+		return results, bulkErr
+	}
+	return results, nil
+}
@@ -0,0 +1,171 @@
+package client
+
+import (
+	"context"
+	"time"
+
+	"github.com/pkg/errors"
+
+	"github.com/mgencur/go-perfrepoclient/pkg/apis"
+)
+
+const defaultPageSize = 100
+
+// ExecutionIterator pages through test executions matching search criteria. Pages are
+// fetched on demand while iterating:
+//
+//	it := c.IterateTestExecutions(ctx, criteria, 100, 0)
+//	for it.Next() {
+//		exec := it.Execution()
+//		...
+//	}
+//	if err := it.Err(); err != nil {
+//		...
+//	}
+type ExecutionIterator struct {
+	client   *PerfRepoClient
+	ctx      context.Context
+	criteria apis.TestExecutionSearch
+	pageSize int
+	limit    int
+	page     []apis.TestExecution
+	pos      int
+	returned int
+	last     bool
+	seen     map[int64]bool
+	current  *apis.TestExecution
+	err      error
+}
+
+// IterateTestExecutions returns an iterator over test executions matching the criteria.
+// Executions are fetched in pages of pageSize (100 when not positive) starting at criteria.LimitFrom.
+// At most limit executions are returned, there's no limit when limit is not positive. A positive
+// criteria.HowMany limits the results as well, the lower of the two limits applies.
+// Results are ordered by date when the criteria don't specify an order so that pages are stable.
+// PerfRepo may return executions sharing the date (or the ordering parameter) in a different order
+// for each page, so executions sharing the value at the end of a page are fetched again with the
+// next page rather than skipped. Executions are returned only once, also when they move between
+// pages because of concurrent changes. The order by test attributes (name, UID, version, group)
+// doesn't have such protection and executions of the same test may be skipped between pages.
+func (c *PerfRepoClient) IterateTestExecutions(ctx context.Context, criteria *apis.TestExecutionSearch, pageSize, limit int) *ExecutionIterator {
+	it := &ExecutionIterator{
+		client: c,
+		ctx:    ctx,
+		limit:  limit,
+		seen:   make(map[int64]bool),
+	}
+	if criteria != nil {
+		it.criteria = *criteria
+	}
+	if it.criteria.OrderBy == apis.UnknownOrderBy {
+		it.criteria.OrderBy = apis.DateAscOrderBy
+	}
+	if it.criteria.HowMany > 0 && (it.limit <= 0 || it.criteria.HowMany < it.limit) {
+		it.limit = it.criteria.HowMany
+	}
+	if pageSize <= 0 {
+		pageSize = defaultPageSize
+	}
+	it.pageSize = pageSize
+	it.criteria.HowMany = pageSize
+	return it
+}
+
+// Next advances the iterator to the next execution. Returns false when there are no more
+// executions or an error occurred, check Err to distinguish these cases.
+func (it *ExecutionIterator) Next() bool {
+	it.current = nil
+	if it.err != nil || (it.limit > 0 && it.returned >= it.limit) {
+		return false
+	}
+	for {
+		if err := it.ctx.Err(); err != nil {
+			it.err = err
+			return false
+		}
+		for it.pos < len(it.page) {
+			exec := &it.page[it.pos]
+			it.pos++
+			if it.seen[exec.ID] {
+				continue
+			}
+			it.seen[exec.ID] = true
+			it.current = exec
+			it.returned++
+			return true
+		}
+		if it.last {
+			return false
+		}
+		if err := it.fetch(); err != nil {
+			it.err = errors.Wrap(err, "Failed to fetch page of test executions")
+			return false
+		}
+	}
+}
+
+func (it *ExecutionIterator) fetch() error {
+	page, err := it.client.searchTestExecutions(it.ctx, &it.criteria)
+	if err != nil {
+		return err
+	}
+	it.page = page
+	it.pos = 0
+	it.last = len(page) < it.criteria.HowMany
+	if it.last {
+		return nil
+	}
+	tied := it.tiedTail(page)
+	if tied == len(page) {
+		//the whole page shares the value, fetch it again with a bigger page until the value ends
+		it.criteria.HowMany *= 2
+		return nil
+	}
+	it.criteria.LimitFrom += len(page) - tied
+	it.criteria.HowMany = it.pageSize
+	return nil
+}
+
+// tiedTail returns the number of executions at the end of the page sharing the ordering value
+// with the last execution. These are fetched again with the next page because PerfRepo doesn't
+// order them consistently across pages.
+func (it *ExecutionIterator) tiedTail(page []apis.TestExecution) int {
+	key := it.orderKey()
+	if key == nil {
+		return 0
+	}
+	last := key(&page[len(page)-1])
+	tied := 1
+	for i := len(page) - 2; i >= 0 && key(&page[i]) == last; i-- {
+		tied++
+	}
+	return tied
+}
+
+// orderKey returns the value executions are ordered by, nil when it's not known to the client
+func (it *ExecutionIterator) orderKey() func(*apis.TestExecution) string {
+	switch it.criteria.OrderBy {
+	case apis.DateAscOrderBy, apis.DateDescOrderBy:
+		return func(exec *apis.TestExecution) string {
+			if exec.Started == nil {
+				return ""
+			}
+			return exec.Started.UTC().Format(time.RFC3339Nano)
+		}
+	case apis.ParameterAscOrderBy, apis.ParameterDescOrderBy:
+		return func(exec *apis.TestExecution) string {
+			return exec.ParametersMap()[it.criteria.OrderByParameter]
+		}
+	}
+	return nil
+}
+
+// Execution returns the current execution. Only valid after Next returned true.
+func (it *ExecutionIterator) Execution() *apis.TestExecution {
+	return it.current
+}
+
+// Err returns the error that stopped the iteration, if any
+func (it *ExecutionIterator) Err() error {
+	return it.err
+}
@@ -0,0 +1,89 @@
+package client
+
+import (
+	"context"
+	"encoding/xml"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"sort"
+	"testing"
+	"time"
+
+	"github.com/mgencur/go-perfrepoclient/pkg/apis"
+)
+
+// tiedServer returns executions ordered by date, executions sharing the date are ordered
+// differently for each request
+func tiedServer(t *testing.T, executions []apis.TestExecution) *httptest.Server {
+	requests := 0
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := ioutil.ReadAll(r.Body)
+		var criteria apis.TestExecutionSearch
+		if err := xml.Unmarshal(body, &criteria); err != nil {
+			t.Error("Failed to read search criteria", err)
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		requests++
+		ordered := append([]apis.TestExecution(nil), executions...)
+		sort.SliceStable(ordered, func(i, j int) bool {
+			if !ordered[i].Started.Equal(ordered[j].Started.Time) {
+				return ordered[i].Started.Before(ordered[j].Started.Time)
+			}
+			if requests%2 == 0 {
+				return ordered[i].ID > ordered[j].ID
+			}
+			return ordered[i].ID < ordered[j].ID
+		})
+		from := criteria.LimitFrom
+		if from > len(ordered) {
+			from = len(ordered)
+		}
+		to := from + criteria.HowMany
+		if to > len(ordered) {
+			to = len(ordered)
+		}
+		out, _ := xml.Marshal(apis.TestExecutions{TestExecutions: ordered[from:to]})
+		w.Write(out)
+	}))
+}
+
+func TestIterateTiedExecutions(t *testing.T) {
+	base := time.Date(2026, time.January, 1, 0, 0, 0, 0, time.UTC)
+	startedAt := []int{0, 1, 1, 1, 2, 3, 3, 3, 3, 3, 4}
+	var executions []apis.TestExecution
+	for i, minute := range startedAt {
+		executions = append(executions, apis.TestExecution{
+			ID:      int64(i + 1),
+			Started: &apis.JaxbTime{Time: base.Add(time.Duration(minute) * time.Minute)},
+		})
+	}
+	server := tiedServer(t, executions)
+	defer server.Close()
+	c := NewClient(server.URL, "user", "pass")
+
+	seen := make(map[int64]bool)
+	it := c.IterateTestExecutions(context.Background(), nil, 2, 0)
+	for it.Next() {
+		if seen[it.Execution().ID] {
+			t.Errorf("Execution %d returned twice", it.Execution().ID)
+		}
+		seen[it.Execution().ID] = true
+	}
+	if it.Err() != nil {
+		t.Fatal("Failed to iterate", it.Err())
+	}
+	if len(seen) != len(executions) {
+		t.Errorf("Expected %d executions, got %v", len(executions), seen)
+	}
+
+	count := 0
+	it = c.IterateTestExecutions(context.Background(), &apis.TestExecutionSearch{HowMany: 5}, 2, 0)
+	for it.Next() {
+		count++
+	}
+	if it.Err() != nil || count != 5 {
+		t.Errorf("Expected 5 executions limited by HowMany, got %d, error: %v", count, it.Err())
+	}
+}
@@ -0,0 +1,47 @@
+package client
+
+import (
+	"context"
+	"encoding/xml"
+	"io"
+
+	"github.com/pkg/errors"
+
+	"github.com/mgencur/go-perfrepoclient/pkg/apis"
+)
+
+// StreamTestExecutions searches for test executions based on the criteria and passes them
+// to fn one at a time as they are decoded from the response, so that memory use doesn't grow
+// with the number of results. The execution passed to fn must not be retained after fn returns
+// unless copied. Decoding stops when fn returns an error, which is then returned.
+func (c *PerfRepoClient) StreamTestExecutions(ctx context.Context, criteria *apis.TestExecutionSearch,
+	fn func(*apis.TestExecution) error) error {
+	resp, err := c.postSearch(ctx, criteria)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	decoder := xml.NewDecoder(resp.Body)
+	var exec apis.TestExecution
+	for {
+		t, err := decoder.Token()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return errors.Wrap(err, "Failed to decode test executions")
+		}
+		start, ok := t.(xml.StartElement)
+		if !ok || start.Name.Local != "testExecution" {
+			continue
+		}
+		exec = apis.TestExecution{}
+		if err := decoder.DecodeElement(&exec, &start); err != nil {
+			return errors.Wrap(err, "Failed to decode test execution")
+		}
+		if err := fn(&exec); err != nil {
+			return err
+		}
+	}
+}
@@ -2,6 +2,7 @@ package client
 
 import (
 	"bytes"
+	"context"
 	"crypto/tls"
 	"crypto/x509"
 	"encoding/base64"
@@ -80,6 +81,21 @@ func (c *PerfRepoClient) CreateTest(test *apis.Test) (id int64, err error) {
 	return id, nil
 }
 
+// NotFoundError is returned when the requested entity doesn't exist in PerfRepo
+type NotFoundError struct {
+	URL string
+}
+
+func (e *NotFoundError) Error() string {
+	return fmt.Sprintf("Entity with given location %s doesn't exist", e.URL)
+}
+
+// IsNotFound returns true when the error, or the error it wraps, is a NotFoundError
+func IsNotFound(err error) bool {
+	_, ok := errors.Cause(err).(*NotFoundError)
+	return ok
+}
+
 func errMsg(req *http.Request, resp *http.Response) error {
 	body, _ := ioutil.ReadAll(resp.Body)
 	return fmt.Errorf("URL: %s, Status: %v, Response: %v", req.URL.String(), resp.Status, string(body))
@@ -138,10 +154,15 @@ func (c *PerfRepoClient) getTest(URL string) (*apis.Test, error) {
 }
 
 func (c *PerfRepoClient) getEntity(URL string) ([]byte, error) {
+	return c.getEntityContext(context.Background(), URL)
+}
+
+func (c *PerfRepoClient) getEntityContext(ctx context.Context, URL string) ([]byte, error) {
 	req, err := c.httpGet(URL)
 	if err != nil {
 		return nil, err
 	}
+	req = req.WithContext(ctx)
 
 	resp, err := c.Client.Do(req)
 	if err != nil {
@@ -152,9 +173,11 @@ func (c *PerfRepoClient) getEntity(URL string) ([]byte, error) {
 	switch resp.StatusCode {
 	case http.StatusOK:
 		if resp.ContentLength == 0 {
-			return nil, fmt.Errorf("Entity with given location %s doesn't exist", URL)
+			return nil, &NotFoundError{URL: URL}
 		}
 		return ioutil.ReadAll(resp.Body)
+	case http.StatusNotFound:
+		return nil, &NotFoundError{URL: URL}
 	default:
 		return nil, errors.Wrap(errMsg(req, resp), "Error while getting entity")
 	}
@@ -248,8 +271,12 @@ func responseBodyAsInt(resp *http.Response) (int64, error) {
 
 // GetTestExecution returns an existing test execution by its identifier or nil if there's an error
 func (c *PerfRepoClient) GetTestExecution(id int64) (*apis.TestExecution, error) {
+	return c.getTestExecution(context.Background(), id)
+}
+
+func (c *PerfRepoClient) getTestExecution(ctx context.Context, id int64) (*apis.TestExecution, error) {
 	URL := fmt.Sprintf("%s/testExecution/%d", c.URL, id)
-	entity, err := c.getEntity(URL)
+	entity, err := c.getEntityContext(ctx, URL)
 	if err != nil {
 		return nil, errors.Wrap(err, "Failed to get metric")
 	}
@@ -270,6 +297,28 @@ func (c *PerfRepoClient) DeleteTestExecution(id int64) error {
 
 // SearchTestExecutions searches for test executions based on criteria passed as the argument.
 func (c *PerfRepoClient) SearchTestExecutions(criteria *apis.TestExecutionSearch) ([]apis.TestExecution, error) {
+	return c.searchTestExecutions(context.Background(), criteria)
+}
+
+func (c *PerfRepoClient) searchTestExecutions(ctx context.Context, criteria *apis.TestExecutionSearch) ([]apis.TestExecution, error) {
+	resp, err := c.postSearch(ctx, criteria)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	body, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return nil, err
+	}
+	var t apis.TestExecutions
+	err = xml.Unmarshal(body, &t)
+	return t.TestExecutions, err
+}
+
+// postSearch sends the search request. Returns the response when PerfRepo accepted the search,
+// the caller is responsible for closing its body.
+func (c *PerfRepoClient) postSearch(ctx context.Context, criteria *apis.TestExecutionSearch) (*http.Response, error) {
 	searchTestExecURL := c.URL + "/testExecution/search"
 
 	marshalled, err := xml.MarshalIndent(criteria, "", "    ")
@@ -281,25 +330,18 @@ func (c *PerfRepoClient) SearchTestExecutions(criteria *apis.TestExecutionSearch
 	if err != nil {
 		return nil, err
 	}
+	req = req.WithContext(ctx)
 
 	resp, err := c.Client.Do(req)
 	if err != nil {
 		return nil, err
 	}
-	defer resp.Body.Close()
 
-	switch resp.StatusCode {
-	case http.StatusOK:
-		body, err := ioutil.ReadAll(resp.Body)
-		if err != nil {
-			return nil, err
-		}
-		var t apis.TestExecutions
-		err = xml.Unmarshal(body, &t)
-		return t.TestExecutions, err
-	default:
+	if resp.StatusCode != http.StatusOK {
+		defer resp.Body.Close()
 		return nil, errors.Wrap(errMsg(req, resp), "Error while searching TestExecutions")
 	}
+	return resp, nil
 }
 
 // CreateAttachment creates a new attachment for a TestExecution identified by its ID.
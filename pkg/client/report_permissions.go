@@ -0,0 +1,122 @@
+package client
+
+import (
+	"encoding/xml"
+	"fmt"
+
+	"github.com/pkg/errors"
+
+	"github.com/mgencur/go-perfrepoclient/pkg/apis"
+)
+
+// PermissionChanges holds the permissions that were added to and removed from
+// a report by ReconcileReportPermissions
+type PermissionChanges struct {
+	Added   []apis.Permission
+	Removed []apis.Permission
+}
+
+// ReconcileReportPermissions makes the permissions of an existing report match the desired
+// permissions. Permissions are compared by access type, access level, group and user, IDs of
+// the desired permissions are ignored. Missing permissions are added first and superfluous
+// permissions are removed afterwards so the report is never left without access.
+// When dryRun is true the changes are only computed and nothing is sent to PerfRepo.
+// Returns the changes that were (or would be) applied. When a request fails, the changes applied
+// before the failure are returned together with the error.
+func (c *PerfRepoClient) ReconcileReportPermissions(reportID int64, desired []apis.Permission, dryRun bool) (*PermissionChanges, error) {
+	report, err := c.GetReport(reportID)
+	if err != nil {
+		return nil, errors.Wrap(err, "Failed to reconcile report permissions")
+	}
+
+	changes := diffPermissions(report.Permissions, desired)
+	if dryRun {
+		return changes, nil
+	}
+
+	applied := &PermissionChanges{}
+	for i := range changes.Added {
+		if err := c.CreateReportPermission(reportPermission(reportID, changes.Added[i])); err != nil {
+			return applied, errors.Wrap(err, fmt.Sprintf("Failed to add permission %+v", changes.Added[i]))
+		}
+		applied.Added = append(applied.Added, changes.Added[i])
+	}
+	for i := range changes.Removed {
+		if err := c.DeleteReportPermission(reportPermission(reportID, changes.Removed[i])); err != nil {
+			return applied, errors.Wrap(err, fmt.Sprintf("Failed to remove permission %+v", changes.Removed[i]))
+		}
+		applied.Removed = append(applied.Removed, changes.Removed[i])
+	}
+
+	//PerfRepo doesn't return IDs of created permissions, read them back from the report
+	report, err = c.GetReport(reportID)
+	if err != nil {
+		return applied, errors.Wrap(err, "Failed to read reconciled report permissions")
+	}
+	for i, added := range applied.Added {
+		for _, p := range report.Permissions {
+			if samePermission(p, added) {
+				applied.Added[i] = p
+				break
+			}
+		}
+	}
+	return applied, nil
+}
+
+// diffPermissions computes which permissions have to be added to and removed from
+// the actual permissions to match the desired ones
+func diffPermissions(actual, desired []apis.Permission) *PermissionChanges {
+	changes := &PermissionChanges{}
+	matched := make([]bool, len(actual))
+	var wanted []apis.Permission
+	for _, d := range desired {
+		duplicate := false
+		for _, w := range wanted {
+			if samePermission(w, d) {
+				duplicate = true
+				break
+			}
+		}
+		if !duplicate {
+			wanted = append(wanted, d)
+		}
+	}
+
+	for _, w := range wanted {
+		found := false
+		for i, a := range actual {
+			if !matched[i] && samePermission(a, w) {
+				matched[i] = true
+				found = true
+				break
+			}
+		}
+		if !found {
+			w.ID = 0
+			changes.Added = append(changes.Added, w)
+		}
+	}
+	for i, a := range actual {
+		if !matched[i] {
+			changes.Removed = append(changes.Removed, a)
+		}
+	}
+	return changes
+}
+
+func samePermission(p1, p2 apis.Permission) bool {
+	return p1.AccessType == p2.AccessType &&
+		p1.AccessLevel == p2.AccessLevel &&
+		p1.GroupID == p2.GroupID &&
+		p1.UserID == p2.UserID
+}
+
+// reportPermission prepares a copy of the permission for standalone permission requests
+func reportPermission(reportID int64, p apis.Permission) *apis.Permission {
+	//PerfRepo expects different name when the permission is a standalone message
+	//and when it's part of a report
+	p.XMLName = xml.Name{Local: "report-permission"}
+	p.ReportID = reportID
+	return &p
+}
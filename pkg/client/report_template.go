@@ -0,0 +1,154 @@
+package client
+
+import (
+	"fmt"
+	"strconv"
+
+	"github.com/pkg/errors"
+
+	"github.com/mgencur/go-perfrepoclient/pkg/apis"
+)
+
+// VariableKind determines how the value of a template variable is resolved
+type VariableKind int
+
+// enumerate values for VariableKind
+const (
+	// TextVariable values are copied to the report property as they are
+	TextVariable VariableKind = iota
+	// TestVariable values are test UIDs which are resolved to test IDs
+	TestVariable
+	// MetricVariable values are metric names which must exist in the related test
+	MetricVariable
+)
+
+// TemplateVariable marks a report property whose value is supplied when
+// the template is instantiated
+type TemplateVariable struct {
+	Property string       // key of the report property
+	Kind     VariableKind // how the supplied value is resolved
+	// TestProperty is the key of the property holding the test the metric belongs to.
+	// Only used for MetricVariable.
+	TestProperty string
+}
+
+// ReportTemplate holds an existing Report together with the properties that
+// differ between its instances
+type ReportTemplate struct {
+	Report    *apis.Report
+	Variables []TemplateVariable
+}
+
+// NewReportTemplate creates a template from the given report. Returns an error when
+// a variable refers to a property the report doesn't have.
+func NewReportTemplate(report *apis.Report, variables ...TemplateVariable) (*ReportTemplate, error) {
+	if report == nil {
+		return nil, errors.New("Invalid Report")
+	}
+	for _, v := range variables {
+		if _, ok := report.Properties[v.Property]; !ok {
+			return nil, fmt.Errorf("Report has no property %s", v.Property)
+		}
+		if v.Kind == MetricVariable {
+			if _, ok := report.Properties[v.TestProperty]; !ok {
+				return nil, fmt.Errorf("Report has no test property %s for metric %s", v.TestProperty, v.Property)
+			}
+		}
+	}
+	return &ReportTemplate{Report: report, Variables: variables}, nil
+}
+
+// InstantiateReportTemplate creates a new Report object from the template without storing it
+// in PerfRepo. The values map holds the value for each template variable keyed by its property.
+// Test UIDs are resolved to IDs and metrics are verified to exist in their tests.
+func (c *PerfRepoClient) InstantiateReportTemplate(template *ReportTemplate, name string, values map[string]string) (*apis.Report, error) {
+	if template == nil || template.Report == nil {
+		return nil, errors.New("Invalid report template")
+	}
+	report := &apis.Report{
+		Name:       name,
+		Type:       template.Report.Type,
+		User:       template.Report.User,
+		Properties: apis.PropertyMap{},
+	}
+	for key, value := range template.Report.Properties {
+		report.Properties[key] = value
+	}
+	for _, p := range template.Report.Permissions {
+		p.ID = 0
+		p.ReportID = 0
+		report.Permissions = append(report.Permissions, p)
+	}
+
+	tests := make(map[string]*apis.Test)
+	for _, v := range template.Variables {
+		value, ok := values[v.Property]
+		if !ok {
+			return nil, fmt.Errorf("Missing value for template variable %s", v.Property)
+		}
+		if v.Kind != TestVariable {
+			continue
+		}
+		test, err := c.GetTestByUID(value)
+		if err != nil {
+			return nil, errors.Wrap(err, fmt.Sprintf("Unable to resolve test for variable %s", v.Property))
+		}
+		tests[v.Property] = test
+		report.Properties[v.Property] = strconv.FormatInt(test.ID, 10)
+	}
+
+	for _, v := range template.Variables {
+		switch v.Kind {
+		case TextVariable:
+			report.Properties[v.Property] = values[v.Property]
+		case MetricVariable:
+			test, err := c.templateTest(report, v.TestProperty, tests)
+			if err != nil {
+				return nil, errors.Wrap(err, fmt.Sprintf("Unable to verify metric for variable %s", v.Property))
+			}
+			metric := values[v.Property]
+			if !hasMetric(test, metric) {
+				return nil, fmt.Errorf("Test %s has no metric %s", test.UID, metric)
+			}
+			report.Properties[v.Property] = metric
+		}
+	}
+	return report, nil
+}
+
+// CreateReportFromTemplate instantiates the template and creates the resulting Report in PerfRepo.
+// Returns the ID of the Report record in database or returns 0 when there was an error.
+func (c *PerfRepoClient) CreateReportFromTemplate(template *ReportTemplate, name string, values map[string]string) (int64, error) {
+	report, err := c.InstantiateReportTemplate(template, name, values)
+	if err != nil {
+		return 0, errors.Wrap(err, "Failed to instantiate report template")
+	}
+	return c.CreateReport(report)
+}
+
+// templateTest returns the test referenced by the given property of the report. Tests
+// resolved from template variables are reused, other tests are fetched by their ID.
+func (c *PerfRepoClient) templateTest(report *apis.Report, property string, tests map[string]*apis.Test) (*apis.Test, error) {
+	if test, ok := tests[property]; ok {
+		return test, nil
+	}
+	id, err := strconv.ParseInt(report.Properties[property], 10, 64)
+	if err != nil {
+		return nil, errors.Wrap(err, fmt.Sprintf("Property %s doesn't hold a test id", property))
+	}
+	test, err := c.GetTest(id)
+	if err != nil {
+		return nil, err
+	}
+	tests[property] = test
+	return test, nil
+}
+
+func hasMetric(test *apis.Test, name string) bool {
+	for _, m := range test.Metrics {
+		if m.Name == name {
+			return true
+		}
+	}
+	return false
+}
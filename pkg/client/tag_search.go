@@ -0,0 +1,44 @@
+package client
+
+import (
+	"strings"
+
+	"github.com/pkg/errors"
+
+	"github.com/mgencur/go-perfrepoclient/pkg/apis"
+)
+
+// SearchTestExecutionsByTags searches for test executions matching both the criteria and the tag
+// expression. Expressions PerfRepo can't evaluate in one search (e.g. OR) are split into several
+// searches whose results are merged and deduplicated by execution ID in the order they were returned.
+// Tags of the criteria are required in addition to the expression. Paging of the criteria applies
+// to each search separately.
+func (c *PerfRepoClient) SearchTestExecutionsByTags(criteria *apis.TestExecutionSearch, expr apis.TagExpr) ([]apis.TestExecution, error) {
+	tagSearches, err := apis.TagSearches(expr)
+	if err != nil {
+		return nil, errors.Wrap(err, "Invalid tag expression")
+	}
+
+	search := apis.TestExecutionSearch{}
+	if criteria != nil {
+		search = *criteria
+	}
+	baseTags := search.Tags
+
+	var executions []apis.TestExecution
+	seen := make(map[int64]bool)
+	for _, tags := range tagSearches {
+		search.Tags = strings.TrimSpace(baseTags + " " + tags)
+		found, err := c.SearchTestExecutions(&search)
+		if err != nil {
+			return nil, errors.Wrap(err, "Failed to search test executions by tags "+search.Tags)
+		}
+		for _, exec := range found {
+			if !seen[exec.ID] {
+				seen[exec.ID] = true
+				executions = append(executions, exec)
+			}
+		}
+	}
+	return executions, nil
+}
@@ -0,0 +1,246 @@
+// Package compare compares metric values of test executions taking into account whether
+// lower (LB) or higher (HB) values of a metric are better.
+package compare
+
+import (
+	"math"
+	"sort"
+
+	"github.com/mgencur/go-perfrepoclient/pkg/apis"
+)
+
+// Verdict is the outcome of comparing a metric value
+type Verdict int
+
+// enumerate values for Verdict
+const (
+	Unchanged Verdict = iota
+	Improved
+	Regressed
+	// Changed is used for significant changes of metrics without a known comparator
+	Changed
+)
+
+var verdictValues = []string{"UNCHANGED", "IMPROVED", "REGRESSED", "CHANGED"}
+
+func (v Verdict) String() string {
+	return verdictValues[v]
+}
+
+// MarshalText implements encoding.TextMarshaler so that verdicts are readable in JSON
+func (v Verdict) MarshalText() ([]byte, error) {
+	return []byte(v.String()), nil
+}
+
+// Threshold determines when a change is significant. A change is significant when it exceeds
+// both the relative and the absolute threshold, a zero threshold is always exceeded by a non-zero change.
+type Threshold struct {
+	Relative float64 // fraction of the baseline value, e.g. 0.05 for 5%
+	Absolute float64 // difference in units of the metric
+}
+
+// Options configures comparisons
+type Options struct {
+	Default Threshold            // threshold for metrics without a specific threshold
+	Metrics map[string]Threshold // thresholds by metric name
+	// Comparators by metric name, take precedence over comparators of the values.
+	// Use ComparatorsOf to get comparators of a test.
+	Comparators map[string]apis.Comparator
+}
+
+// Delta holds the comparison of a single metric value
+type Delta struct {
+	Metric     string
+	Parameters []apis.ValueParameter // parameters distinguishing values of multi-value metrics
+	Comparator apis.Comparator
+	Baseline   float64
+	Candidate  float64
+	// BaselineCount and CandidateCount are the numbers of values with the same metric and parameters
+	// in the executions, Baseline and Candidate are medians when they are greater than one
+	BaselineCount  int
+	CandidateCount int
+	Difference     float64 // Candidate - Baseline
+	Change         float64 // Difference relative to Baseline, ±Inf when Baseline is zero
+	Verdict        Verdict
+}
+
+// Result holds the comparison of two test executions
+type Result struct {
+	Deltas          []Delta
+	OnlyInBaseline  []apis.Value // values without a counterpart in the candidate
+	OnlyInCandidate []apis.Value // values without a counterpart in the baseline
+}
+
+// ComparatorsOf returns comparators of the test metrics by metric name
+func ComparatorsOf(test *apis.Test) map[string]apis.Comparator {
+	comparators := make(map[string]apis.Comparator)
+	for _, m := range test.Metrics {
+		comparators[m.Name] = m.Comparator
+	}
+	return comparators
+}
+
+// Executions compares values of the candidate execution to values of the baseline execution.
+// Values of multi-value metrics are matched by their value parameters. Repeated values of a metric
+// with the same parameters are aggregated to their median on both sides. Deltas are ordered by
+// metric name and parameters.
+func Executions(baseline, candidate *apis.TestExecution, opts Options) *Result {
+	result := &Result{}
+	baseValues, candValues := groupValues(baseline.Values), groupValues(candidate.Values)
+	for key, c := range candValues {
+		b, ok := baseValues[key]
+		if !ok {
+			continue
+		}
+		comparator := opts.comparator(key.metric, b[0].MetricComparator, c[0].MetricComparator)
+		d := Values(key.metric, comparator, median(b), median(c), opts.threshold(key.metric))
+		d.Parameters = c[0].Parameters
+		d.BaselineCount, d.CandidateCount = len(b), len(c)
+		result.Deltas = append(result.Deltas, d)
+	}
+	for _, b := range baseline.Values {
+		if _, ok := candValues[keyOf(&b)]; !ok {
+			result.OnlyInBaseline = append(result.OnlyInBaseline, b)
+		}
+	}
+	for _, c := range candidate.Values {
+		if _, ok := baseValues[keyOf(&c)]; !ok {
+			result.OnlyInCandidate = append(result.OnlyInCandidate, c)
+		}
+	}
+	sort.SliceStable(result.Deltas, func(i, j int) bool {
+		ki, kj := deltaKey(&result.Deltas[i]), deltaKey(&result.Deltas[j])
+		if ki.metric != kj.metric {
+			return ki.metric < kj.metric
+		}
+		return ki.params < kj.params
+	})
+	return result
+}
+
+// groupValues groups values by metric and parameters
+func groupValues(values []apis.Value) map[valueKey][]apis.Value {
+	groups := make(map[valueKey][]apis.Value)
+	for _, v := range values {
+		key := keyOf(&v)
+		groups[key] = append(groups[key], v)
+	}
+	return groups
+}
+
+func median(values []apis.Value) float64 {
+	results := make([]float64, len(values))
+	for i, v := range values {
+		results[i] = v.Result
+	}
+	sort.Float64s(results)
+	n := len(results)
+	if n%2 == 1 {
+		return results[n/2]
+	}
+	return (results[n/2-1] + results[n/2]) / 2
+}
+
+// Values compares a candidate value of a metric to a baseline value
+func Values(metric string, comparator apis.Comparator, baseline, candidate float64, threshold Threshold) Delta {
+	d := Delta{
+		Metric:     metric,
+		Comparator: comparator,
+		Baseline:   baseline,
+		Candidate:  candidate,
+		Difference: candidate - baseline,
+		Change:     RelativeChange(baseline, candidate),
+	}
+	d.Verdict = Judge(comparator, d.Difference, d.Change, threshold)
+	return d
+}
+
+// RelativeChange returns the change of the candidate relative to the baseline. Returns ±Inf
+// when the baseline is zero and the candidate isn't, 0 when both are zero.
+func RelativeChange(baseline, candidate float64) float64 {
+	diff := candidate - baseline
+	if baseline == 0 {
+		if diff == 0 {
+			return 0
+		}
+		return math.Inf(int(math.Copysign(1, diff)))
+	}
+	return diff / math.Abs(baseline)
+}
+
+// Judge decides whether a change of a metric is an improvement or a regression according to
+// the comparator. Returns Unchanged when the change doesn't exceed the threshold.
+func Judge(comparator apis.Comparator, difference, change float64, threshold Threshold) Verdict {
+	if difference == 0 ||
+		math.Abs(difference) <= threshold.Absolute ||
+		math.Abs(change) <= threshold.Relative {
+		return Unchanged
+	}
+	switch comparator {
+	case apis.LBComparator:
+		if difference < 0 {
+			return Improved
+		}
+		return Regressed
+	case apis.HBComparator:
+		if difference > 0 {
+			return Improved
+		}
+		return Regressed
+	default:
+		return Changed
+	}
+}
+
+// Regressions returns deltas with the Regressed verdict
+func (r *Result) Regressions() []Delta {
+	return r.withVerdict(Regressed)
+}
+
+// Improvements returns deltas with the Improved verdict
+func (r *Result) Improvements() []Delta {
+	return r.withVerdict(Improved)
+}
+
+func (r *Result) withVerdict(verdict Verdict) []Delta {
+	var deltas []Delta
+	for _, d := range r.Deltas {
+		if d.Verdict == verdict {
+			deltas = append(deltas, d)
+		}
+	}
+	return deltas
+}
+
+func (o *Options) threshold(metric string) Threshold {
+	if t, ok := o.Metrics[metric]; ok {
+		return t
+	}
+	return o.Default
+}
+
+// comparator returns the configured comparator of the metric or the first known comparator of its values
+func (o *Options) comparator(metric string, fromValues ...apis.Comparator) apis.Comparator {
+	if c, ok := o.Comparators[metric]; ok && c != apis.UnknownComparator {
+		return c
+	}
+	for _, c := range fromValues {
+		if c != apis.UnknownComparator {
+			return c
+		}
+	}
+	return apis.UnknownComparator
+}
+
+type valueKey struct {
+	metric string
+	params string
+}
+
+func keyOf(v *apis.Value) valueKey {
+	return valueKey{metric: v.MetricName, params: v.ParametersKey()}
+}
+
+func deltaKey(d *Delta) valueKey {
+	return keyOf(&apis.Value{MetricName: d.Metric, Parameters: d.Parameters})
+}
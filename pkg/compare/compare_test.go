@@ -0,0 +1,125 @@
+package compare
+
+import (
+	"math"
+	"testing"
+
+	"github.com/mgencur/go-perfrepoclient/pkg/apis"
+)
+
+func execution(values ...apis.Value) *apis.TestExecution {
+	return &apis.TestExecution{Values: values}
+}
+
+func value(metric string, result float64, client string) apis.Value {
+	v := apis.Value{MetricName: metric, Result: result}
+	if client != "" {
+		v.Parameters = []apis.ValueParameter{{Name: "client", Value: client}}
+	}
+	return v
+}
+
+func TestExecutions(t *testing.T) {
+	baseline := execution(
+		value("latency", 100, ""),
+		value("throughput", 1000, ""),
+		value("multimetric", 20, "1"),
+		value("multimetric", 40, "2"),
+		value("removed", 1, ""),
+	)
+	candidate := execution(
+		value("latency", 120, ""),
+		value("throughput", 1020, ""),
+		value("multimetric", 45, "2"),
+		value("multimetric", 10, "1"),
+		value("added", 1, ""),
+	)
+	opts := Options{
+		Default: Threshold{Relative: 0.05},
+		Comparators: map[string]apis.Comparator{
+			"latency":     apis.LBComparator,
+			"throughput":  apis.HBComparator,
+			"multimetric": apis.HBComparator,
+		},
+	}
+
+	result := Executions(baseline, candidate, opts)
+
+	expected := []struct {
+		metric  string
+		client  string
+		verdict Verdict
+	}{
+		{"latency", "", Regressed},
+		{"multimetric", "1", Regressed},
+		{"multimetric", "2", Improved},
+		{"throughput", "", Unchanged},
+	}
+	if len(result.Deltas) != len(expected) {
+		t.Fatalf("Expected %d deltas, got %+v", len(expected), result.Deltas)
+	}
+	for i, e := range expected {
+		d := result.Deltas[i]
+		client := ""
+		if len(d.Parameters) > 0 {
+			client = d.Parameters[0].Value
+		}
+		if d.Metric != e.metric || client != e.client || d.Verdict != e.verdict {
+			t.Errorf("Expected %s client=%s %v, got %+v", e.metric, e.client, e.verdict, d)
+		}
+	}
+	if math.Abs(result.Deltas[0].Change-0.2) > 1e-9 || result.Deltas[0].Difference != 20 {
+		t.Errorf("Unexpected change of latency %+v", result.Deltas[0])
+	}
+	if len(result.OnlyInBaseline) != 1 || result.OnlyInBaseline[0].MetricName != "removed" ||
+		len(result.OnlyInCandidate) != 1 || result.OnlyInCandidate[0].MetricName != "added" {
+		t.Errorf("Unmatched values not reported: %+v", result)
+	}
+	if len(result.Regressions()) != 2 || len(result.Improvements()) != 1 {
+		t.Errorf("Unexpected regressions %+v", result.Regressions())
+	}
+}
+
+func TestJudgeThresholds(t *testing.T) {
+	cases := []struct {
+		comparator apis.Comparator
+		baseline   float64
+		candidate  float64
+		threshold  Threshold
+		verdict    Verdict
+	}{
+		{apis.LBComparator, 100, 90, Threshold{}, Improved},
+		{apis.HBComparator, 100, 90, Threshold{}, Regressed},
+		{apis.HBComparator, 100, 100, Threshold{}, Unchanged},
+		{apis.HBComparator, 100, 90, Threshold{Relative: 0.1}, Unchanged},
+		{apis.HBComparator, 100, 89, Threshold{Relative: 0.1}, Regressed},
+		{apis.HBComparator, 100, 89, Threshold{Relative: 0.1, Absolute: 20}, Unchanged},
+		{apis.UnknownComparator, 100, 89, Threshold{}, Changed},
+		{apis.LBComparator, 0, 1, Threshold{Relative: 0.5}, Regressed},
+	}
+	for _, c := range cases {
+		d := Values("metric", c.comparator, c.baseline, c.candidate, c.threshold)
+		if d.Verdict != c.verdict {
+			t.Errorf("Expected %v for %+v, got %v", c.verdict, c, d.Verdict)
+		}
+	}
+}
+
+func TestExecutionsDuplicates(t *testing.T) {
+	baseline := execution(value("latency", 100, ""), value("latency", 300, ""), value("latency", 110, ""),
+		value("removed", 1, ""), value("removed", 2, ""))
+	candidate := execution(value("latency", 130, ""), value("latency", 90, ""))
+	opts := Options{Comparators: map[string]apis.Comparator{"latency": apis.LBComparator}}
+
+	result := Executions(baseline, candidate, opts)
+	if len(result.Deltas) != 1 {
+		t.Fatalf("Expected 1 delta, got %+v", result.Deltas)
+	}
+	d := result.Deltas[0]
+	if d.Baseline != 110 || d.Candidate != 110 || d.BaselineCount != 3 || d.CandidateCount != 2 || d.Verdict != Unchanged {
+		t.Errorf("Expected medians of repeated values, got %+v", d)
+	}
+	if len(result.OnlyInBaseline) != 2 || len(result.OnlyInCandidate) != 0 {
+		t.Errorf("Unexpected unmatched values %+v, %+v", result.OnlyInBaseline, result.OnlyInCandidate)
+	}
+}
@@ -0,0 +1,261 @@
+// Package drift detects changes of the environment between compared executions, e.g. a different
+// CPU model or JVM flags, which make comparisons of their metrics meaningless.
+package drift
+
+import (
+	"fmt"
+	"path"
+	"sort"
+	"strings"
+
+	"github.com/mgencur/go-perfrepoclient/pkg/apis"
+	"github.com/mgencur/go-perfrepoclient/pkg/compare"
+)
+
+// Class is the classification of a difference
+type Class int
+
+// enumerate values for Class
+const (
+	Unclassified  Class = iota // no rule matched, the comparison is allowed with a warning
+	Expected                   // the difference is expected, e.g. commit or build number
+	Environmental              // the environment changed, the comparison is blocked
+	Ignored                    // the difference is not reported
+)
+
+var classValues = []string{"unclassified", "expected", "environmental", "ignored"}
+
+func (c *Class) String() string {
+	return classValues[*c]
+}
+
+// Action is the recommended handling of a comparison
+type Action int
+
+// enumerate values for Action
+const (
+	Allow Action = iota
+	Warn
+	Block
+)
+
+var actionValues = []string{"allow", "warn", "block"}
+
+func (a *Action) String() string {
+	return actionValues[*a]
+}
+
+// Rule classifies parameters whose name matches the pattern, see path.Match for its syntax.
+// Tag rules match tag names.
+type Rule struct {
+	Pattern string
+	Class   Class
+}
+
+// DefaultRules classify common names of build and environment parameters. Environmental rules come
+// first so that e.g. jvm_version is environmental while app_version is expected.
+var DefaultRules = []Rule{
+	{"*cpu*", Environmental},
+	{"*kernel*", Environmental},
+	{"*heap*", Environmental},
+	{"*jvm*", Environmental},
+	{"*java*", Environmental},
+	{"*memory*", Environmental},
+	{"os", Environmental},
+	{"os_*", Environmental},
+	{"*_os", Environmental},
+	{"*host*", Environmental},
+	{"*commit*", Expected},
+	{"*revision*", Expected},
+	{"*build*", Expected},
+	{"*version*", Expected},
+}
+
+// Options configures the check
+type Options struct {
+	Rules       []Rule // parameter rules, the first matching rule wins, DefaultRules when nil
+	CompareTags bool   // report tags present only in one of the sides
+	TagRules    []Rule // tag rules, tags are unclassified when no rule matches
+	// WarnOnly downgrades Block to Warn so that environmental differences don't stop comparisons
+	WarnOnly bool
+}
+
+// Difference is a parameter or tag which differs between the baseline and the candidate
+type Difference struct {
+	Name      string
+	Tag       bool     // the difference is a tag present only in one of the sides
+	Baseline  []string // distinct values in the baseline executions, "" when missing in some of them
+	Candidate []string // distinct values in the candidate executions, "" when missing in some of them
+	Class     Class
+}
+
+func (d Difference) String() string {
+	if d.Tag {
+		side := "candidate"
+		if len(d.Baseline) > 0 {
+			side = "baseline"
+		}
+		return fmt.Sprintf("tag %s only in %s (%s)", d.Name, side, d.Class.String())
+	}
+	return fmt.Sprintf("%s: %s -> %s (%s)", d.Name, strings.Join(d.Baseline, "|"),
+		strings.Join(d.Candidate, "|"), d.Class.String())
+}
+
+// Result holds the differences and the recommended action
+type Result struct {
+	Differences []Difference // ordered by tags and name
+	Action      Action
+}
+
+// Err returns a *BlockedError when the comparison should be blocked
+func (r *Result) Err() error {
+	if r.Action != Block {
+		return nil
+	}
+	return &BlockedError{Differences: r.Environmental()}
+}
+
+// Environmental returns the environmental differences
+func (r *Result) Environmental() []Difference {
+	var diffs []Difference
+	for _, d := range r.Differences {
+		if d.Class == Environmental {
+			diffs = append(diffs, d)
+		}
+	}
+	return diffs
+}
+
+// BlockedError is returned when environmental differences block a comparison
+type BlockedError struct {
+	Differences []Difference
+}
+
+func (e *BlockedError) Error() string {
+	parts := make([]string, len(e.Differences))
+	for i, d := range e.Differences {
+		parts[i] = d.String()
+	}
+	return "Environment changed between compared executions: " + strings.Join(parts, ", ")
+}
+
+// Compare checks the environment of two executions and compares them when it's allowed
+func Compare(baseline, candidate *apis.TestExecution, opts Options, compareOpts compare.Options) (*compare.Result, *Result, error) {
+	result := Check(baseline, candidate, opts)
+	if err := result.Err(); err != nil {
+		return nil, result, err
+	}
+	return compare.Executions(baseline, candidate, compareOpts), result, nil
+}
+
+// Check diffs parameters (and optionally tags) of the baseline and candidate executions
+func Check(baseline, candidate *apis.TestExecution, opts Options) *Result {
+	return CheckSets([]apis.TestExecution{*baseline}, []apis.TestExecution{*candidate}, opts)
+}
+
+// CheckSets diffs parameters (and optionally tags) of sets of executions, e.g. repeated runs.
+// A parameter differs when the sets of its values differ.
+func CheckSets(baseline, candidate []apis.TestExecution, opts Options) *Result {
+	rules := opts.Rules
+	if rules == nil {
+		rules = DefaultRules
+	}
+	result := &Result{}
+
+	baseParams, candParams := parameterValues(baseline), parameterValues(candidate)
+	for _, name := range unionKeys(baseParams, candParams) {
+		b, c := baseParams[name], candParams[name]
+		if strings.Join(b, "\n") == strings.Join(c, "\n") {
+			continue
+		}
+		result.add(Difference{Name: name, Baseline: b, Candidate: c, Class: classify(rules, name)})
+	}
+
+	if opts.CompareTags {
+		baseTags, candTags := tagNames(baseline), tagNames(candidate)
+		for _, name := range unionKeys(baseTags, candTags) {
+			if len(baseTags[name]) > 0 && len(candTags[name]) > 0 {
+				continue
+			}
+			result.add(Difference{Name: name, Tag: true, Baseline: baseTags[name], Candidate: candTags[name],
+				Class: classify(opts.TagRules, name)})
+		}
+	}
+
+	for _, d := range result.Differences {
+		switch {
+		case d.Class == Environmental:
+			result.Action = Block
+		case d.Class == Unclassified && result.Action < Warn:
+			result.Action = Warn
+		}
+	}
+	if opts.WarnOnly && result.Action == Block {
+		result.Action = Warn
+	}
+	return result
+}
+
+func (r *Result) add(d Difference) {
+	if d.Class != Ignored {
+		r.Differences = append(r.Differences, d)
+	}
+}
+
+func classify(rules []Rule, name string) Class {
+	lower := strings.ToLower(name)
+	for _, r := range rules {
+		if ok, _ := path.Match(strings.ToLower(r.Pattern), lower); ok {
+			return r.Class
+		}
+	}
+	return Unclassified
+}
+
+// parameterValues returns sorted distinct values of each parameter, "" stands for executions
+// without the parameter
+func parameterValues(executions []apis.TestExecution) map[string][]string {
+	names := make(map[string]bool)
+	for i := range executions {
+		for _, p := range executions[i].Parameters {
+			names[p.Name] = true
+		}
+	}
+	values := make(map[string][]string)
+	for name := range names {
+		distinct := make(map[string]bool)
+		for i := range executions {
+			distinct[executions[i].ParametersMap()[name]] = true
+		}
+		for v := range distinct {
+			values[name] = append(values[name], v)
+		}
+		sort.Strings(values[name])
+	}
+	return values
+}
+
+// tagNames returns tags present in any of the executions
+func tagNames(executions []apis.TestExecution) map[string][]string {
+	tags := make(map[string][]string)
+	for i := range executions {
+		for _, t := range executions[i].Tags {
+			tags[t.Name] = []string{t.Name}
+		}
+	}
+	return tags
+}
+
+func unionKeys(a, b map[string][]string) []string {
+	keys := make([]string, 0, len(a)+len(b))
+	for k := range a {
+		keys = append(keys, k)
+	}
+	for k := range b {
+		if _, ok := a[k]; !ok {
+			keys = append(keys, k)
+		}
+	}
+	sort.Strings(keys)
+	return keys
+}
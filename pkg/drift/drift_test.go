@@ -0,0 +1,131 @@
+package drift
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/mgencur/go-perfrepoclient/pkg/apis"
+	"github.com/mgencur/go-perfrepoclient/pkg/compare"
+)
+
+// execution creates an execution with parameters given as name/value pairs
+func execution(params ...string) apis.TestExecution {
+	exec := apis.TestExecution{Values: []apis.Value{{MetricName: "m", Result: 100}}}
+	for i := 0; i < len(params); i += 2 {
+		exec.Parameters = append(exec.Parameters, apis.TestExecutionParameter{Name: params[i], Value: params[i+1]})
+	}
+	return exec
+}
+
+func TestCheckDefaultRules(t *testing.T) {
+	cases := []struct {
+		name      string
+		parameter string
+		class     Class
+		action    Action
+	}{
+		{"environmental before expected", "jvm_version", Environmental, Block},
+		{"expected", "app_version", Expected, Allow},
+		{"case insensitive", "CPU_Model", Environmental, Block},
+		{"commit", "git_commit", Expected, Allow},
+		{"os prefix", "os_release", Environmental, Block},
+		{"unclassified", "dataset", Unclassified, Warn},
+	}
+	for _, c := range cases {
+		baseline, candidate := execution(c.parameter, "1"), execution(c.parameter, "2")
+		result := Check(&baseline, &candidate, Options{})
+		expected := []Difference{{Name: c.parameter, Baseline: []string{"1"}, Candidate: []string{"2"}, Class: c.class}}
+		if !reflect.DeepEqual(result.Differences, expected) || result.Action != c.action {
+			t.Errorf("%s: expected %+v and %s, got %+v and %s", c.name, expected, c.action.String(),
+				result.Differences, result.Action.String())
+		}
+	}
+}
+
+func TestCheckSets(t *testing.T) {
+	baseline := []apis.TestExecution{execution("heap", "2g", "build", "1"), execution("heap", "2g", "build", "2")}
+	candidate := []apis.TestExecution{execution("heap", "2g", "build", "3"), execution("build", "4")}
+	result := CheckSets(baseline, candidate, Options{})
+	expected := []Difference{
+		{Name: "build", Baseline: []string{"1", "2"}, Candidate: []string{"3", "4"}, Class: Expected},
+		//the heap is missing in one of the candidate executions
+		{Name: "heap", Baseline: []string{"2g"}, Candidate: []string{"", "2g"}, Class: Environmental},
+	}
+	if !reflect.DeepEqual(result.Differences, expected) || result.Action != Block {
+		t.Errorf("Expected %+v, got %+v", expected, result)
+	}
+
+	//the same values in a different order don't differ
+	result = CheckSets(baseline, []apis.TestExecution{baseline[1], baseline[0]}, Options{})
+	if len(result.Differences) != 0 || result.Action != Allow {
+		t.Errorf("Expected no differences, got %+v", result)
+	}
+}
+
+func TestCheckTags(t *testing.T) {
+	baseline, candidate := execution(), execution()
+	baseline.Tags = []apis.Tag{{Name: "shared"}, {Name: "nightly"}}
+	candidate.Tags = []apis.Tag{{Name: "shared"}, {Name: "container"}}
+
+	if result := Check(&baseline, &candidate, Options{}); len(result.Differences) != 0 {
+		t.Errorf("Expected tags to be compared only on demand, got %+v", result.Differences)
+	}
+
+	opts := Options{CompareTags: true, TagRules: []Rule{{"container", Environmental}, {"night*", Ignored}}}
+	result := Check(&baseline, &candidate, opts)
+	expected := []Difference{{Name: "container", Tag: true, Candidate: []string{"container"}, Class: Environmental}}
+	if !reflect.DeepEqual(result.Differences, expected) || result.Action != Block {
+		t.Errorf("Expected %+v, got %+v", expected, result)
+	}
+	if s := result.Differences[0].String(); s != "tag container only in candidate (environmental)" {
+		t.Errorf("Unexpected description %q", s)
+	}
+}
+
+func TestCheckAction(t *testing.T) {
+	rules := []Rule{{"env*", Environmental}, {"build", Expected}, {"noise", Ignored}}
+	cases := []struct {
+		name       string
+		parameters []string
+		warnOnly   bool
+		action     Action
+	}{
+		{"no differences", nil, false, Allow},
+		{"ignored", []string{"noise"}, false, Allow},
+		{"expected", []string{"build"}, false, Allow},
+		{"unclassified", []string{"build", "other"}, false, Warn},
+		{"environmental", []string{"other", "env", "build"}, false, Block},
+		{"warn only", []string{"other", "env"}, true, Warn},
+	}
+	for _, c := range cases {
+		var base, cand []string
+		for _, p := range c.parameters {
+			base, cand = append(base, p, "a"), append(cand, p, "b")
+		}
+		baseline, candidate := execution(base...), execution(cand...)
+		result := Check(&baseline, &candidate, Options{Rules: rules, WarnOnly: c.warnOnly})
+		if result.Action != c.action {
+			t.Errorf("%s: expected %s, got %s", c.name, c.action.String(), result.Action.String())
+		}
+		if (result.Err() != nil) != (c.action == Block) {
+			t.Errorf("%s: unexpected error %v", c.name, result.Err())
+		}
+	}
+}
+
+func TestCompare(t *testing.T) {
+	baseline, candidate := execution("kernel", "5.14", "build", "1"), execution("kernel", "6.1", "build", "2")
+	cmp, result, err := Compare(&baseline, &candidate, Options{}, compare.Options{})
+	blocked, ok := err.(*BlockedError)
+	if !ok || cmp != nil || result.Action != Block {
+		t.Fatalf("Expected a blocked comparison, got %v, %+v and %v", cmp, result, err)
+	}
+	if len(blocked.Differences) != 1 || blocked.Differences[0].Name != "kernel" {
+		t.Errorf("Expected the kernel difference only, got %+v", blocked.Differences)
+	}
+
+	cmp, result, err = Compare(&baseline, &candidate, Options{WarnOnly: true}, compare.Options{})
+	if err != nil || cmp == nil || result.Action != Warn {
+		t.Errorf("Expected a comparison with a warning, got %v, %+v and %v", cmp, result, err)
+	}
+}
@@ -0,0 +1,189 @@
+// Package influence ranks execution parameters by how much of the variance of a metric they explain.
+package influence
+
+import (
+	"math"
+	"sort"
+	"strconv"
+
+	"github.com/pkg/errors"
+
+	"github.com/mgencur/go-perfrepoclient/pkg/apis"
+	"github.com/mgencur/go-perfrepoclient/pkg/client"
+	"github.com/mgencur/go-perfrepoclient/pkg/stats"
+)
+
+// minNumericLevels is the minimal number of distinct values of a numeric parameter analyzed by correlation
+const minNumericLevels = 3
+
+// Kind is the kind of an execution parameter
+type Kind int
+
+// enumerate values for Kind
+const (
+	Categorical Kind = iota // analyzed by one-way ANOVA
+	Numeric                 // analyzed by correlation
+)
+
+var kindValues = []string{"categorical", "numeric"}
+
+func (k *Kind) String() string {
+	return kindValues[*k]
+}
+
+// Options configures the analysis
+type Options struct {
+	Parameters string   // value parameters of a multi-value metric, see apis.Value.ParametersKey
+	Include    []string // parameters to analyze, all parameters when empty
+	Exclude    []string // parameters not analyzed, e.g. unique build identifiers
+}
+
+// Influence describes how much a parameter explains the variance of the metric
+type Influence struct {
+	Parameter string
+	Kind      Kind
+	Levels    int // distinct values of the parameter, a missing parameter counts as a value for categorical ones
+	Samples   int
+	// Explained is the estimated fraction of the variance explained by the parameter: omega squared
+	// for categorical and adjusted r squared for numeric parameters
+	Explained float64
+	PValue    float64
+	ANOVA     stats.ANOVAResult // set for categorical parameters
+	Pearson   float64           // set for numeric parameters
+	Spearman  float64           // set for numeric parameters
+	// Means of the metric by value of the parameter, ordered by value
+	Means []LevelMean
+}
+
+// LevelMean is the mean of the metric for a value of a parameter
+type LevelMean struct {
+	Value string
+	Mean  float64
+	Count int
+}
+
+// AnalyzeSearch searches executions matching the criteria and ranks their parameters by influence
+// on the metric
+func AnalyzeSearch(c *client.PerfRepoClient, criteria *apis.TestExecutionSearch, metric string,
+	opts Options) ([]Influence, error) {
+	executions, err := c.SearchTestExecutions(criteria)
+	if err != nil {
+		return nil, errors.Wrap(err, "Failed to fetch executions for influence analysis")
+	}
+	return Analyze(executions, metric, opts), nil
+}
+
+// Analyze ranks parameters of the executions by the fraction of the variance of the metric they
+// explain, most influential first. Parameters whose values are all numbers with at least three
+// distinct values are correlated with the metric, other parameters are analyzed by one-way ANOVA.
+// Parameters with a single value and categorical parameters with a different value in each
+// execution can't explain anything and are left out. Multiple values of the metric in an execution are averaged.
+func Analyze(executions []apis.TestExecution, metric string, opts Options) []Influence {
+	type sample struct {
+		params map[string]string
+		result float64
+	}
+	var samples []sample
+	names := make(map[string]bool)
+	for i := range executions {
+		sum, count := 0.0, 0
+		for _, v := range executions[i].MetricValues(metric) {
+			if v.ParametersKey() == opts.Parameters {
+				sum += v.Result
+				count++
+			}
+		}
+		if count == 0 {
+			continue
+		}
+		params := executions[i].ParametersMap()
+		for name := range params {
+			if selected(name, opts) {
+				names[name] = true
+			}
+		}
+		samples = append(samples, sample{params: params, result: sum / float64(count)})
+	}
+
+	var influences []Influence
+	for name := range names {
+		groups := make(map[string][]float64)
+		numeric := true
+		for _, s := range samples {
+			value, ok := s.params[name]
+			if _, err := strconv.ParseFloat(value, 64); !ok || err != nil {
+				numeric = false
+			}
+			groups[value] = append(groups[value], s.result)
+		}
+		if len(groups) < 2 {
+			continue
+		}
+		inf := Influence{Parameter: name, Levels: len(groups), Samples: len(samples)}
+		for value, g := range groups {
+			inf.Means = append(inf.Means, LevelMean{Value: value, Mean: stats.Mean(g), Count: len(g)})
+		}
+		sort.Slice(inf.Means, func(i, j int) bool {
+			return apis.LessParameterValue(inf.Means[i].Value, inf.Means[j].Value)
+		})
+
+		if numeric && len(groups) >= minNumericLevels {
+			inf.Kind = Numeric
+			xs := make([]float64, len(samples))
+			ys := make([]float64, len(samples))
+			for i, s := range samples {
+				xs[i], _ = strconv.ParseFloat(s.params[name], 64)
+				ys[i] = s.result
+			}
+			var test stats.TestResult
+			inf.Pearson, test = stats.Pearson(xs, ys)
+			inf.Spearman, _ = stats.Spearman(xs, ys)
+			inf.PValue = test.PValue
+			n := float64(len(samples))
+			inf.Explained = math.Max(0, 1-(1-inf.Pearson*inf.Pearson)*(n-1)/(n-2))
+		} else {
+			if len(groups) >= len(samples) {
+				continue
+			}
+			inf.Kind = Categorical
+			values := make([][]float64, 0, len(groups))
+			for _, m := range inf.Means {
+				values = append(values, groups[m.Value])
+			}
+			inf.ANOVA = stats.OneWayANOVA(values)
+			inf.PValue = inf.ANOVA.PValue
+			inf.Explained = inf.ANOVA.OmegaSquared
+		}
+		if math.IsNaN(inf.Explained) {
+			continue
+		}
+		influences = append(influences, inf)
+	}
+	sort.Slice(influences, func(i, j int) bool {
+		if influences[i].Explained != influences[j].Explained {
+			return influences[i].Explained > influences[j].Explained
+		}
+		if influences[i].PValue != influences[j].PValue {
+			return influences[i].PValue < influences[j].PValue
+		}
+		return influences[i].Parameter < influences[j].Parameter
+	})
+	return influences
+}
+
+func selected(name string, opts Options) bool {
+	for _, e := range opts.Exclude {
+		if e == name {
+			return false
+		}
+	}
+	if len(opts.Include) == 0 {
+		return true
+	}
+	for _, i := range opts.Include {
+		if i == name {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,87 @@
+package influence
+
+import (
+	"strconv"
+	"testing"
+
+	"github.com/mgencur/go-perfrepoclient/pkg/apis"
+)
+
+// executions returns executions whose result depends mostly on gc and slightly on threads,
+// heap is missing in some of them, env is the same and build is unique in all of them
+func executions() []apis.TestExecution {
+	rows := []struct {
+		gc, threads, heap string
+		result            float64
+	}{
+		{"G1", "1", "2", 100}, {"G1", "2", "2", 102}, {"G1", "4", "4", 101}, {"G1", "8", "", 104},
+		{"ZGC", "1", "2", 200}, {"ZGC", "2", "4", 203}, {"ZGC", "4", "", 201}, {"ZGC", "8", "4", 205},
+	}
+	execs := make([]apis.TestExecution, len(rows))
+	for i, r := range rows {
+		params := []apis.TestExecutionParameter{
+			{Name: "gc", Value: r.gc}, {Name: "threads", Value: r.threads},
+			{Name: "env", Value: "prod"}, {Name: "build", Value: "b" + strconv.Itoa(i)},
+		}
+		if r.heap != "" {
+			params = append(params, apis.TestExecutionParameter{Name: "heap", Value: r.heap})
+		}
+		execs[i] = apis.TestExecution{Parameters: params, Values: []apis.Value{{MetricName: "m", Result: r.result}}}
+	}
+	return execs
+}
+
+func TestAnalyze(t *testing.T) {
+	influences := Analyze(executions(), "m", Options{})
+	kinds := map[string]Kind{"gc": Categorical, "threads": Numeric, "heap": Categorical}
+	if len(influences) != len(kinds) {
+		t.Fatalf("Expected influences of %v, got %+v", kinds, influences)
+	}
+	for i, inf := range influences {
+		if kind, ok := kinds[inf.Parameter]; !ok || inf.Kind != kind || inf.Samples != 8 {
+			t.Errorf("Unexpected influence %+v", inf)
+		}
+		if i > 0 && inf.Explained > influences[i-1].Explained {
+			t.Errorf("Influences not ordered by explained variance %+v", influences)
+		}
+	}
+	gc := influences[0]
+	if gc.Parameter != "gc" || gc.Explained < 0.9 || gc.PValue > 0.001 || gc.Levels != 2 {
+		t.Errorf("Expected gc to be the most influential, got %+v", gc)
+	}
+	if gc.Means[0] != (LevelMean{Value: "G1", Mean: 101.75, Count: 4}) {
+		t.Errorf("Unexpected means %+v", gc.Means)
+	}
+	for _, inf := range influences {
+		//the missing heap counts as a level and makes the parameter categorical
+		if inf.Parameter == "heap" && (inf.Levels != 3 || inf.Means[2].Value != "") {
+			t.Errorf("Unexpected heap influence %+v", inf)
+		}
+		if inf.Parameter == "threads" && (inf.Levels != 4 || inf.Pearson <= 0 || inf.Spearman <= 0) {
+			t.Errorf("Unexpected threads influence %+v", inf)
+		}
+	}
+}
+
+func TestAnalyzeSelection(t *testing.T) {
+	cases := []struct {
+		name       string
+		opts       Options
+		parameters []string
+	}{
+		{"include", Options{Include: []string{"threads", "env", "build"}}, []string{"threads"}},
+		{"exclude", Options{Exclude: []string{"gc", "heap"}}, []string{"threads"}},
+		{"include and exclude", Options{Include: []string{"gc", "threads"}, Exclude: []string{"threads"}}, []string{"gc"}},
+		{"other value parameters", Options{Parameters: "client=1"}, nil},
+	}
+	for _, c := range cases {
+		influences := Analyze(executions(), "m", c.opts)
+		var parameters []string
+		for _, inf := range influences {
+			parameters = append(parameters, inf.Parameter)
+		}
+		if len(parameters) != len(c.parameters) || (len(parameters) > 0 && parameters[0] != c.parameters[0]) {
+			t.Errorf("%s: expected %v, got %v", c.name, c.parameters, parameters)
+		}
+	}
+}
@@ -0,0 +1,326 @@
+// Package leaderboard ranks configurations of a test, encoded as execution parameters, by a metric
+// and finds Pareto-optimal configurations for two metrics.
+package leaderboard
+
+import (
+	"math"
+	"sort"
+	"strings"
+
+	"github.com/pkg/errors"
+
+	"github.com/mgencur/go-perfrepoclient/pkg/apis"
+	"github.com/mgencur/go-perfrepoclient/pkg/client"
+	"github.com/mgencur/go-perfrepoclient/pkg/compare"
+	"github.com/mgencur/go-perfrepoclient/pkg/stats"
+)
+
+const defaultConfidence = 0.95
+
+// Options configures the ranking
+type Options struct {
+	GroupBy    []string // execution parameters forming a configuration, e.g. gc, heap and pool_size
+	Parameters string   // value parameters of multi-value metrics, see apis.Value.ParametersKey
+	Confidence float64  // confidence of the intervals of means, 0.95 by default
+	// Comparators by metric name, take precedence over comparators of the test metrics and of the values
+	Comparators map[string]apis.Comparator
+}
+
+// Aggregate describes values of a metric in executions of a configuration
+type Aggregate struct {
+	Count    int
+	Mean     float64
+	Median   float64
+	StdDev   float64        // zero for a single value
+	Interval stats.Interval // Student's t confidence interval of the mean, NaN for a single value
+}
+
+// Entry is a ranked configuration
+type Entry struct {
+	Key           string // configuration as name=value pairs in order of Options.GroupBy
+	Configuration []apis.TestExecutionParameter
+	Executions    []*apis.TestExecution
+	Aggregate
+	Rank int // starting at 1
+	// Contender is true when the interval overlaps the interval of the leader, i.e. the configuration
+	// may be as good as the leader
+	Contender bool
+}
+
+// Board holds configurations ranked by a metric, the best first
+type Board struct {
+	Metric     string
+	Comparator apis.Comparator
+	Entries    []Entry
+}
+
+// ParetoEntry is a configuration evaluated by two metrics
+type ParetoEntry struct {
+	Key           string
+	Configuration []apis.TestExecutionParameter
+	First         Aggregate
+	Second        Aggregate
+	Optimal       bool // no other configuration is better in one metric and at least as good in the other
+	DominatedBy   int  // number of configurations dominating this one
+}
+
+// ParetoBoard holds configurations evaluated by two metrics. Pareto-optimal configurations come first,
+// entries are further ordered by the first metric.
+type ParetoBoard struct {
+	Metrics     [2]string
+	Comparators [2]apis.Comparator
+	Entries     []ParetoEntry
+}
+
+// Optimal returns the Pareto-optimal configurations
+func (b *ParetoBoard) Optimal() []ParetoEntry {
+	var optimal []ParetoEntry
+	for _, e := range b.Entries {
+		if e.Optimal {
+			optimal = append(optimal, e)
+		}
+	}
+	return optimal
+}
+
+// RankSearch searches executions matching the criteria and ranks their configurations by the metric.
+// When the criteria restrict the test UID, comparators of the test metrics are used for metrics
+// without a comparator in Options.Comparators.
+func RankSearch(c *client.PerfRepoClient, criteria *apis.TestExecutionSearch, metric string, opts Options) (*Board, error) {
+	executions, err := c.SearchTestExecutions(criteria)
+	if err != nil {
+		return nil, errors.Wrap(err, "Failed to fetch executions for leaderboard")
+	}
+	if opts, err = withTestComparators(c, criteria, opts, metric); err != nil {
+		return nil, err
+	}
+	return Rank(executions, metric, opts)
+}
+
+// ParetoSearch searches executions matching the criteria and evaluates their configurations by two metrics.
+// Comparators of the test metrics are used as in RankSearch.
+func ParetoSearch(c *client.PerfRepoClient, criteria *apis.TestExecutionSearch, first, second string,
+	opts Options) (*ParetoBoard, error) {
+	executions, err := c.SearchTestExecutions(criteria)
+	if err != nil {
+		return nil, errors.Wrap(err, "Failed to fetch executions for leaderboard")
+	}
+	if opts, err = withTestComparators(c, criteria, opts, first, second); err != nil {
+		return nil, err
+	}
+	return Pareto(executions, first, second, opts)
+}
+
+// withTestComparators returns options with comparators of the searched test added for the metrics
+// without a comparator, the test is fetched only when needed
+func withTestComparators(c *client.PerfRepoClient, criteria *apis.TestExecutionSearch, opts Options,
+	metrics ...string) (Options, error) {
+	if criteria.TestUID == "" {
+		return opts, nil
+	}
+	var missing []string
+	for _, m := range metrics {
+		if opts.Comparators[m] == apis.UnknownComparator {
+			missing = append(missing, m)
+		}
+	}
+	if len(missing) == 0 {
+		return opts, nil
+	}
+	test, err := c.GetTestByUID(criteria.TestUID)
+	if err != nil {
+		return opts, errors.Wrap(err, "Failed to fetch test for leaderboard")
+	}
+	//copy the comparators so that the caller's map is not modified
+	comparators := make(map[string]apis.Comparator, len(opts.Comparators)+len(missing))
+	for m, c := range opts.Comparators {
+		comparators[m] = c
+	}
+	defined := compare.ComparatorsOf(test)
+	for _, m := range missing {
+		if c := defined[m]; c != apis.UnknownComparator {
+			comparators[m] = c
+		}
+	}
+	opts.Comparators = comparators
+	return opts, nil
+}
+
+// Rank groups the executions into configurations and ranks them by the mean of the metric, the best
+// first according to the metric comparator. Executions without the metric are ignored.
+func Rank(executions []apis.TestExecution, metric string, opts Options) (*Board, error) {
+	opts.setDefaults()
+	groups := groupExecutions(executions, opts.GroupBy)
+	comparator, err := comparatorOf(executions, metric, opts)
+	if err != nil {
+		return nil, err
+	}
+
+	board := &Board{Metric: metric, Comparator: comparator}
+	for _, g := range groups {
+		values := metricValues(g.executions, metric, opts.Parameters)
+		if len(values) == 0 {
+			continue
+		}
+		board.Entries = append(board.Entries, Entry{
+			Key:           g.key,
+			Configuration: g.configuration,
+			Executions:    g.executions,
+			Aggregate:     aggregate(values, opts.Confidence),
+		})
+	}
+	sort.SliceStable(board.Entries, func(i, j int) bool {
+		return better(comparator, board.Entries[i].Mean, board.Entries[j].Mean)
+	})
+	for i := range board.Entries {
+		e := &board.Entries[i]
+		e.Rank = i + 1
+		if i > 0 && e.Mean == board.Entries[i-1].Mean {
+			e.Rank = board.Entries[i-1].Rank
+		}
+		leader := board.Entries[0].Interval
+		e.Contender = i == 0 || overlaps(leader, e.Interval)
+	}
+	return board, nil
+}
+
+// Pareto groups the executions into configurations and finds those which are Pareto-optimal for
+// the two metrics, e.g. throughput and latency. Configurations missing any of the metrics are ignored.
+func Pareto(executions []apis.TestExecution, first, second string, opts Options) (*ParetoBoard, error) {
+	opts.setDefaults()
+	board := &ParetoBoard{Metrics: [2]string{first, second}}
+	for i, m := range board.Metrics {
+		c, err := comparatorOf(executions, m, opts)
+		if err != nil {
+			return nil, err
+		}
+		board.Comparators[i] = c
+	}
+
+	for _, g := range groupExecutions(executions, opts.GroupBy) {
+		firstValues := metricValues(g.executions, first, opts.Parameters)
+		secondValues := metricValues(g.executions, second, opts.Parameters)
+		if len(firstValues) == 0 || len(secondValues) == 0 {
+			continue
+		}
+		board.Entries = append(board.Entries, ParetoEntry{
+			Key:           g.key,
+			Configuration: g.configuration,
+			First:         aggregate(firstValues, opts.Confidence),
+			Second:        aggregate(secondValues, opts.Confidence),
+		})
+	}
+	for i := range board.Entries {
+		a := &board.Entries[i]
+		for j := range board.Entries {
+			if i != j && dominates(board.Comparators, &board.Entries[j], a) {
+				a.DominatedBy++
+			}
+		}
+		a.Optimal = a.DominatedBy == 0
+	}
+	sort.SliceStable(board.Entries, func(i, j int) bool {
+		a, b := board.Entries[i], board.Entries[j]
+		if a.Optimal != b.Optimal {
+			return a.Optimal
+		}
+		return better(board.Comparators[0], a.First.Mean, b.First.Mean)
+	})
+	return board, nil
+}
+
+// dominates returns true when a is at least as good as b in both metrics and better in one of them
+func dominates(comparators [2]apis.Comparator, a, b *ParetoEntry) bool {
+	firstBetter := better(comparators[0], a.First.Mean, b.First.Mean)
+	secondBetter := better(comparators[1], a.Second.Mean, b.Second.Mean)
+	firstWorse := better(comparators[0], b.First.Mean, a.First.Mean)
+	secondWorse := better(comparators[1], b.Second.Mean, a.Second.Mean)
+	return !firstWorse && !secondWorse && (firstBetter || secondBetter)
+}
+
+func better(comparator apis.Comparator, a, b float64) bool {
+	if comparator == apis.LBComparator {
+		return a < b
+	}
+	return a > b
+}
+
+func overlaps(a, b stats.Interval) bool {
+	return a.Lower <= b.Upper && b.Lower <= a.Upper
+}
+
+func aggregate(values []float64, confidence float64) Aggregate {
+	a := Aggregate{Count: len(values), Mean: stats.Mean(values), Median: stats.Median(values)}
+	if len(values) < 2 {
+		a.Interval = stats.Interval{Lower: math.NaN(), Upper: math.NaN()}
+		return a
+	}
+	a.StdDev = stats.StdDev(values)
+	n := float64(len(values))
+	margin := stats.StudentTQuantile(1-(1-confidence)/2, n-1) * a.StdDev / math.Sqrt(n)
+	a.Interval = stats.Interval{Lower: a.Mean - margin, Upper: a.Mean + margin}
+	return a
+}
+
+type group struct {
+	key           string
+	configuration []apis.TestExecutionParameter
+	executions    []*apis.TestExecution
+}
+
+// groupExecutions groups the executions by values of the parameters in order of first occurrence
+func groupExecutions(executions []apis.TestExecution, parameters []string) []*group {
+	var groups []*group
+	byKey := make(map[string]*group)
+	for i := range executions {
+		params := executions[i].ParametersMap()
+		configuration := make([]apis.TestExecutionParameter, len(parameters))
+		parts := make([]string, len(parameters))
+		for j, name := range parameters {
+			configuration[j] = apis.TestExecutionParameter{Name: name, Value: params[name]}
+			parts[j] = name + "=" + params[name]
+		}
+		key := strings.Join(parts, ", ")
+		g, ok := byKey[key]
+		if !ok {
+			g = &group{key: key, configuration: configuration}
+			byKey[key] = g
+			groups = append(groups, g)
+		}
+		g.executions = append(g.executions, &executions[i])
+	}
+	return groups
+}
+
+// metricValues returns values of the metric in the executions
+func metricValues(executions []*apis.TestExecution, metric, parameters string) []float64 {
+	var values []float64
+	for _, exec := range executions {
+		for _, v := range exec.MetricValues(metric) {
+			if v.ParametersKey() == parameters {
+				values = append(values, v.Result)
+			}
+		}
+	}
+	return values
+}
+
+func comparatorOf(executions []apis.TestExecution, metric string, opts Options) (apis.Comparator, error) {
+	if c, ok := opts.Comparators[metric]; ok && c != apis.UnknownComparator {
+		return c, nil
+	}
+	for i := range executions {
+		for _, v := range executions[i].MetricValues(metric) {
+			if v.MetricComparator != apis.UnknownComparator {
+				return v.MetricComparator, nil
+			}
+		}
+	}
+	return apis.UnknownComparator, errors.Errorf("Comparator of metric %s is unknown", metric)
+}
+
+func (o *Options) setDefaults() {
+	if o.Confidence <= 0 {
+		o.Confidence = defaultConfidence
+	}
+}
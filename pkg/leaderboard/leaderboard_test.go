@@ -0,0 +1,129 @@
+package leaderboard
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/mgencur/go-perfrepoclient/pkg/apis"
+	"github.com/mgencur/go-perfrepoclient/pkg/client"
+)
+
+func execution(gc string, throughput, latency float64) apis.TestExecution {
+	return apis.TestExecution{
+		Parameters: []apis.TestExecutionParameter{{Name: "gc", Value: gc}, {Name: "run", Value: "x"}},
+		Values: []apis.Value{
+			{MetricName: "throughput", Result: throughput, MetricComparator: apis.HBComparator},
+			{MetricName: "latency", Result: latency, MetricComparator: apis.LBComparator},
+		},
+	}
+}
+
+func executions() []apis.TestExecution {
+	return []apis.TestExecution{
+		execution("G1", 1000, 20), execution("G1", 1010, 21), execution("G1", 990, 19),
+		execution("ZGC", 950, 10), execution("ZGC", 960, 11), execution("ZGC", 940, 9),
+		execution("Serial", 800, 30), execution("Serial", 810, 31), execution("Serial", 790, 29),
+		execution("Parallel", 1005, 25), execution("Parallel", 995, 24), execution("Parallel", 1015, 26),
+	}
+}
+
+func TestRank(t *testing.T) {
+	board, err := Rank(executions(), "throughput", Options{GroupBy: []string{"gc"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := []string{"gc=Parallel", "gc=G1", "gc=ZGC", "gc=Serial"}
+	if len(board.Entries) != len(expected) {
+		t.Fatalf("Unexpected entries %+v", board.Entries)
+	}
+	for i, key := range expected {
+		if board.Entries[i].Key != key || board.Entries[i].Rank != i+1 {
+			t.Errorf("Expected %s at rank %d, got %+v", key, i+1, board.Entries[i])
+		}
+	}
+	if !board.Entries[1].Contender || board.Entries[2].Contender || board.Entries[3].Contender {
+		t.Errorf("Unexpected contenders %+v", board.Entries)
+	}
+
+	if _, err := Rank(executions(), "missing", Options{}); err == nil {
+		t.Error("Expected an error for a metric with unknown comparator")
+	}
+}
+
+func TestPareto(t *testing.T) {
+	board, err := Pareto(executions(), "throughput", "latency", Options{GroupBy: []string{"gc"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	optimal := board.Optimal()
+	if len(optimal) != 3 || optimal[0].Key != "gc=Parallel" || optimal[1].Key != "gc=G1" || optimal[2].Key != "gc=ZGC" {
+		t.Fatalf("Unexpected Pareto front %+v", optimal)
+	}
+	if last := board.Entries[3]; last.Key != "gc=Serial" || last.Optimal || last.DominatedBy != 3 {
+		t.Errorf("Unexpected dominated configuration %+v", last)
+	}
+}
+
+const testXML = `<test id="1" name="service" groupId="perfrepouser" uid="service">
+<metrics><metric name="throughput" comparator="HB"/><metric name="latency" comparator="LB"/></metrics></test>`
+
+// values of the executions don't carry comparators of their metrics
+const executionsXML = `<testExecutions>
+<testExecution id="1" name="e1" testId="1" testUid="service"><parameters><parameter name="gc" value="G1"/></parameters>
+<values><value metricName="throughput" result="1000"/><value metricName="latency" result="20"/></values></testExecution>
+<testExecution id="2" name="e2" testId="1" testUid="service"><parameters><parameter name="gc" value="ZGC"/></parameters>
+<values><value metricName="throughput" result="900"/><value metricName="latency" result="10"/></values></testExecution>
+</testExecutions>`
+
+func TestSearchTestComparators(t *testing.T) {
+	testRequests := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/rest/test/uid/service":
+			testRequests++
+			w.Write([]byte(testXML))
+		case "/rest/testExecution/search":
+			w.Write([]byte(executionsXML))
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer server.Close()
+	c := client.NewClient(server.URL, "user", "pass")
+	criteria := &apis.TestExecutionSearch{TestUID: "service"}
+	opts := Options{GroupBy: []string{"gc"}}
+
+	board, err := RankSearch(c, criteria, "latency", opts)
+	if err != nil {
+		t.Fatal("Failed to rank configurations", err)
+	}
+	if board.Comparator != apis.LBComparator || board.Entries[0].Key != "gc=ZGC" {
+		t.Errorf("Expected the comparator of the test metric, got %+v", board)
+	}
+
+	//the explicit comparator takes precedence and the test is not fetched
+	override := map[string]apis.Comparator{"latency": apis.HBComparator}
+	opts.Comparators = override
+	if board, err = RankSearch(c, criteria, "latency", opts); err != nil || board.Entries[0].Key != "gc=G1" {
+		t.Errorf("Expected the overridden comparator, got %+v and %v", board, err)
+	}
+	if testRequests != 1 {
+		t.Errorf("Expected the test to be fetched once, got %d requests", testRequests)
+	}
+
+	pareto, err := ParetoSearch(c, criteria, "throughput", "latency", opts)
+	if err != nil {
+		t.Fatal("Failed to evaluate configurations", err)
+	}
+	if pareto.Comparators != [2]apis.Comparator{apis.HBComparator, apis.HBComparator} || len(pareto.Optimal()) != 1 {
+		t.Errorf("Unexpected Pareto board %+v", pareto)
+	}
+	if len(override) != 1 {
+		t.Errorf("Comparators of the options modified %+v", override)
+	}
+
+	if _, err := RankSearch(c, &apis.TestExecutionSearch{}, "latency", Options{}); err == nil {
+		t.Error("Expected an error for a metric with unknown comparator")
+	}
+}
@@ -0,0 +1,219 @@
+// Package noise estimates run-to-run variability of metrics from repeated executions and
+// recommends regression thresholds and numbers of repetitions.
+package noise
+
+import (
+	"encoding/json"
+	"io"
+	"math"
+	"sort"
+	"strings"
+
+	"github.com/pkg/errors"
+
+	"github.com/mgencur/go-perfrepoclient/pkg/apis"
+	"github.com/mgencur/go-perfrepoclient/pkg/client"
+	"github.com/mgencur/go-perfrepoclient/pkg/compare"
+	"github.com/mgencur/go-perfrepoclient/pkg/stats"
+)
+
+const (
+	defaultConfidence   = 0.95
+	defaultPower        = 0.8
+	defaultRepetitions  = 1
+	defaultTargetEffect = 0.05
+)
+
+// Options configures the profiling. Zero values select defaults.
+type Options struct {
+	Confidence   float64 // confidence of the comparison, 0.95 by default
+	Power        float64 // probability of detecting the effect, 0.8 by default
+	Repetitions  int     // repetitions per compared side the MDE is computed for, 1 by default
+	TargetEffect float64 // relative effect the recommended repetitions are computed for, 0.05 by default
+	// IgnoreParameters are execution parameters which don't make executions different,
+	// e.g. build numbers or host names
+	IgnoreParameters []string
+}
+
+// Profile describes the run-to-run variability of a metric sharing the same value parameters
+type Profile struct {
+	Metric     string  `json:"metric"`
+	Parameters string  `json:"parameters,omitempty"` // value parameters, see apis.Value.ParametersKey
+	Groups     int     `json:"groups"`               // groups of repeated executions contributing to the estimate
+	Samples    int     `json:"samples"`              // values in those groups
+	CV         float64 `json:"cv"`                   // pooled coefficient of variation within the groups
+	// MDE is the minimum detectable relative effect between Options.Repetitions executions per side
+	MDE float64 `json:"mde"`
+	// Repetitions is the number of executions per side needed to detect Options.TargetEffect
+	Repetitions int `json:"repetitions"`
+}
+
+// Report holds profiles of all metrics together with the options they were computed for
+type Report struct {
+	Confidence   float64   `json:"confidence"`
+	Power        float64   `json:"power"`
+	Repetitions  int       `json:"repetitions"`
+	TargetEffect float64   `json:"targetEffect"`
+	Profiles     []Profile `json:"profiles"`
+}
+
+// ProfileSearch searches executions matching the criteria and profiles their noise
+func ProfileSearch(c *client.PerfRepoClient, criteria *apis.TestExecutionSearch, opts Options) (*Report, error) {
+	executions, err := c.SearchTestExecutions(criteria)
+	if err != nil {
+		return nil, errors.Wrap(err, "Failed to fetch executions for noise profiling")
+	}
+	return ProfileExecutions(executions, opts), nil
+}
+
+// ProfileExecutions groups the executions by their parameters and estimates the variability of each
+// metric within the groups. Only groups with at least two executions contribute. The variability
+// is pooled across groups as relative variance so that groups with different means are comparable.
+func ProfileExecutions(executions []apis.TestExecution, opts Options) *Report {
+	opts.setDefaults()
+	type profileKey struct {
+		metric string
+		params string
+	}
+	// values by metric and group of identical executions
+	groups := make(map[profileKey]map[string][]float64)
+	for i := range executions {
+		group := groupKey(&executions[i], opts.IgnoreParameters)
+		for j := range executions[i].Values {
+			v := &executions[i].Values[j]
+			key := profileKey{metric: v.MetricName, params: v.ParametersKey()}
+			if groups[key] == nil {
+				groups[key] = make(map[string][]float64)
+			}
+			groups[key][group] = append(groups[key][group], v.Result)
+		}
+	}
+
+	report := &Report{
+		Confidence:   opts.Confidence,
+		Power:        opts.Power,
+		Repetitions:  opts.Repetitions,
+		TargetEffect: opts.TargetEffect,
+	}
+	z := stats.NormalQuantile(1-(1-opts.Confidence)/2) + stats.NormalQuantile(opts.Power)
+	for key, byGroup := range groups {
+		p := Profile{Metric: key.metric, Parameters: key.params}
+		sumSquares, df := 0.0, 0
+		for _, values := range byGroup {
+			mean := stats.Mean(values)
+			if len(values) < 2 || mean == 0 {
+				continue
+			}
+			p.Groups++
+			p.Samples += len(values)
+			sumSquares += stats.Variance(values) / (mean * mean) * float64(len(values)-1)
+			df += len(values) - 1
+		}
+		if df == 0 {
+			continue
+		}
+		p.CV = math.Sqrt(sumSquares / float64(df))
+		p.MDE = MinimumDetectableEffect(p.CV, opts.Repetitions, z)
+		p.Repetitions = RequiredRepetitions(p.CV, opts.TargetEffect, z)
+		report.Profiles = append(report.Profiles, p)
+	}
+	sort.Slice(report.Profiles, func(i, j int) bool {
+		if report.Profiles[i].Metric != report.Profiles[j].Metric {
+			return report.Profiles[i].Metric < report.Profiles[j].Metric
+		}
+		return report.Profiles[i].Parameters < report.Profiles[j].Parameters
+	})
+	return report
+}
+
+// MinimumDetectableEffect returns the smallest relative difference between means of two sets of n
+// executions detectable by a two-sided test, where z is the sum of the normal quantiles of the
+// confidence and the power
+func MinimumDetectableEffect(cv float64, n int, z float64) float64 {
+	return z * cv * math.Sqrt(2/float64(n))
+}
+
+// RequiredRepetitions returns the number of executions per side needed to detect the relative
+// effect, see MinimumDetectableEffect
+func RequiredRepetitions(cv, effect, z float64) int {
+	n := 2 * math.Pow(z*cv/effect, 2)
+	if n < 1 {
+		return 1
+	}
+	return int(math.Ceil(n))
+}
+
+// Thresholds returns the MDE of each metric as a relative threshold. The greatest MDE is used
+// for multi-value metrics.
+func (r *Report) Thresholds() map[string]compare.Threshold {
+	thresholds := make(map[string]compare.Threshold)
+	for _, p := range r.Profiles {
+		if t, ok := thresholds[p.Metric]; !ok || p.MDE > t.Relative {
+			thresholds[p.Metric] = compare.Threshold{Relative: p.MDE}
+		}
+	}
+	return thresholds
+}
+
+// CompareOptions returns a copy of the options with thresholds of the profiled metrics replaced
+// by their MDE
+func (r *Report) CompareOptions(opts compare.Options) compare.Options {
+	metrics := make(map[string]compare.Threshold)
+	for m, t := range opts.Metrics {
+		metrics[m] = t
+	}
+	for m, t := range r.Thresholds() {
+		metrics[m] = t
+	}
+	opts.Metrics = metrics
+	return opts
+}
+
+// WriteJSON writes the report in JSON format
+func (r *Report) WriteJSON(w io.Writer) error {
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	return errors.Wrap(encoder.Encode(r), "Failed to write noise profile")
+}
+
+// Load reads a report written by WriteJSON
+func Load(r io.Reader) (*Report, error) {
+	var report Report
+	if err := json.NewDecoder(r).Decode(&report); err != nil {
+		return nil, errors.Wrap(err, "Failed to read noise profile")
+	}
+	return &report, nil
+}
+
+// groupKey identifies executions with identical parameters
+func groupKey(exec *apis.TestExecution, ignore []string) string {
+	var parts []string
+	for _, p := range exec.SortedParameters() {
+		ignored := false
+		for _, name := range ignore {
+			if p.Name == name {
+				ignored = true
+				break
+			}
+		}
+		if !ignored {
+			parts = append(parts, p.Name+"="+p.Value)
+		}
+	}
+	return strings.Join(parts, "\n")
+}
+
+func (o *Options) setDefaults() {
+	if o.Confidence <= 0 {
+		o.Confidence = defaultConfidence
+	}
+	if o.Power <= 0 {
+		o.Power = defaultPower
+	}
+	if o.Repetitions <= 0 {
+		o.Repetitions = defaultRepetitions
+	}
+	if o.TargetEffect <= 0 {
+		o.TargetEffect = defaultTargetEffect
+	}
+}
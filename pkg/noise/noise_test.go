@@ -0,0 +1,114 @@
+package noise
+
+import (
+	"bytes"
+	"math"
+	"reflect"
+	"testing"
+
+	"github.com/mgencur/go-perfrepoclient/pkg/apis"
+	"github.com/mgencur/go-perfrepoclient/pkg/compare"
+)
+
+func execution(gc, build string, result float64) apis.TestExecution {
+	return apis.TestExecution{
+		Parameters: []apis.TestExecutionParameter{{Name: "gc", Value: gc}, {Name: "build", Value: build}},
+		Values:     []apis.Value{{MetricName: "m", Result: result}},
+	}
+}
+
+func executions() []apis.TestExecution {
+	return []apis.TestExecution{
+		execution("G1", "1", 90), execution("G1", "2", 100), execution("G1", "3", 110),
+		execution("ZGC", "4", 990), execution("ZGC", "5", 1010),
+		execution("Serial", "6", 500),
+		execution("Epsilon", "7", 0), execution("Epsilon", "8", 0),
+	}
+}
+
+func TestProfileExecutions(t *testing.T) {
+	//each execution has a different build, no executions are repeated
+	if report := ProfileExecutions(executions(), Options{}); len(report.Profiles) != 0 {
+		t.Errorf("Expected no profiles without repeated executions, got %+v", report.Profiles)
+	}
+
+	report := ProfileExecutions(executions(), Options{IgnoreParameters: []string{"build"}})
+	if len(report.Profiles) != 1 {
+		t.Fatalf("Expected a single profile, got %+v", report.Profiles)
+	}
+	p := report.Profiles[0]
+	//G1 has relative variance 100/100^2 with 2 degrees of freedom, ZGC 200/1000^2 with 1,
+	//the Serial group has a single value and the Epsilon group zero mean
+	cv := math.Sqrt((0.01*2 + 0.0002) / 3)
+	if p.Groups != 2 || p.Samples != 5 || math.Abs(p.CV-cv) > 1e-9 {
+		t.Errorf("Expected 2 groups, 5 samples and CV %v, got %+v", cv, p)
+	}
+	z := 1.959964 + 0.841621
+	if math.Abs(p.MDE-z*cv*math.Sqrt2) > 1e-5 || p.Repetitions != int(math.Ceil(2*math.Pow(z*cv/0.05, 2))) {
+		t.Errorf("Unexpected MDE and repetitions %+v", p)
+	}
+}
+
+func TestMinimumDetectableEffect(t *testing.T) {
+	cases := []struct {
+		cv, z       float64
+		n           int
+		mde         float64
+		effect      float64
+		repetitions int
+	}{
+		{0.1, 2.8, 2, 0.28, 0.05, 63}, // 2 * (2.8 * 0.1 / 0.05)^2 = 62.72
+		{0.05, 2, 8, 0.05, 0.1, 2},    // 2 * (2 * 0.05 / 0.1)^2 = 2
+		{0.001, 2.8, 1, 0.0028 * math.Sqrt2, 0.05, 1},
+	}
+	for _, c := range cases {
+		if mde := MinimumDetectableEffect(c.cv, c.n, c.z); math.Abs(mde-c.mde) > 1e-12 {
+			t.Errorf("Expected MDE %v for %+v, got %v", c.mde, c, mde)
+		}
+		if n := RequiredRepetitions(c.cv, c.effect, c.z); n != c.repetitions {
+			t.Errorf("Expected %d repetitions for %+v, got %d", c.repetitions, c, n)
+		}
+	}
+}
+
+func TestReportJSONRoundTrip(t *testing.T) {
+	report := ProfileExecutions(executions(), Options{IgnoreParameters: []string{"build"}, Repetitions: 3})
+	var buf bytes.Buffer
+	if err := report.WriteJSON(&buf); err != nil {
+		t.Fatal("Failed to write report", err)
+	}
+	loaded, err := Load(&buf)
+	if err != nil {
+		t.Fatal("Failed to load report", err)
+	}
+	if !reflect.DeepEqual(report, loaded) {
+		t.Errorf("Expected %+v, got %+v", report, loaded)
+	}
+	if _, err := Load(bytes.NewBufferString("{")); err == nil {
+		t.Error("Expected an error for malformed report")
+	}
+}
+
+func TestCompareOptions(t *testing.T) {
+	report := &Report{Profiles: []Profile{
+		{Metric: "m", Parameters: "client=1", MDE: 0.02},
+		{Metric: "m", Parameters: "client=2", MDE: 0.04},
+		{Metric: "new", MDE: 0.1},
+	}}
+	opts := compare.Options{
+		Default: compare.Threshold{Relative: 0.05},
+		Metrics: map[string]compare.Threshold{"m": {Relative: 0.5}, "other": {Absolute: 1}},
+	}
+	result := report.CompareOptions(opts)
+	expected := map[string]compare.Threshold{
+		"m":     {Relative: 0.04}, // the greatest MDE of the multi-value metric
+		"new":   {Relative: 0.1},
+		"other": {Absolute: 1},
+	}
+	if !reflect.DeepEqual(result.Metrics, expected) || result.Default != opts.Default {
+		t.Errorf("Expected thresholds %+v, got %+v", expected, result)
+	}
+	if opts.Metrics["m"].Relative != 0.5 {
+		t.Error("Original options modified")
+	}
+}
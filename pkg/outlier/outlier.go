@@ -0,0 +1,261 @@
+// Package outlier identifies executions whose metric values deviate suspiciously from the rest
+// of the metric history, e.g. runs affected by noisy neighbours or thermal throttling.
+package outlier
+
+import (
+	"math"
+	"sort"
+
+	"github.com/pkg/errors"
+
+	"github.com/mgencur/go-perfrepoclient/pkg/apis"
+	"github.com/mgencur/go-perfrepoclient/pkg/client"
+	"github.com/mgencur/go-perfrepoclient/pkg/stats"
+)
+
+const (
+	// DefaultTag is the tag added to outlier executions when Options.Tag is empty
+	DefaultTag = "outlier"
+
+	defaultMADThreshold = 3.5
+	defaultIQRThreshold = 1.5
+	defaultMinSamples   = 5
+
+	//scales the median and mean absolute deviation to the standard deviation of a normal distribution
+	madScale     = 1.4826
+	meanADScale  = 1.2533
+	madZeroLimit = 1e-12
+)
+
+// Method is the rule deciding whether a value is an outlier
+type Method int
+
+// enumerate values for Method
+const (
+	MAD Method = iota // modified z-score based on the median absolute deviation
+	IQR               // Tukey's fences based on the interquartile range
+)
+
+// Options configures the detection. Zero values select defaults.
+type Options struct {
+	Method Method
+	// Threshold is the modified z-score for MAD (3.5 by default) and the multiple of
+	// the interquartile range beyond the quartiles for IQR (1.5 by default)
+	Threshold  float64
+	Metrics    []string // metrics to check, all metrics when empty
+	MinSamples int      // histories with fewer values are skipped, 5 by default
+	Tag        string   // tag added to outlier executions by Flag, DefaultTag when empty
+}
+
+// Outlier is a suspicious value of a test execution
+type Outlier struct {
+	Execution  *apis.TestExecution
+	Metric     string
+	Parameters string // value parameters, see apis.Value.ParametersKey
+	Result     float64
+	Median     float64 // median of the metric history
+	Lower      float64 // values below are outliers
+	Upper      float64 // values above are outliers
+	Score      float64 // modified z-score for MAD, distance from the quartile in IQRs for IQR
+}
+
+// FlagResult holds the outliers found by Flag and their executions
+type FlagResult struct {
+	Outliers []Outlier
+	Untagged []int64 // outlier executions without the tag in the search results, tagged unless it's a dry run
+	Tagged   []int64 // executions tagged by Flag
+}
+
+// Flag searches executions matching the criteria, finds outliers among them and adds the tag to
+// the outlier executions which don't have it yet. When dryRun is true nothing is sent to PerfRepo
+// and the executions that would be tagged are only listed as Untagged. When tagging fails, the result
+// lists the executions tagged so far together with the error.
+func Flag(c *client.PerfRepoClient, criteria *apis.TestExecutionSearch, opts Options, dryRun bool) (*FlagResult, error) {
+	opts.setDefaults()
+	executions, err := c.SearchTestExecutions(criteria)
+	if err != nil {
+		return nil, errors.Wrap(err, "Failed to fetch executions for outlier detection")
+	}
+	result := &FlagResult{Outliers: Find(executions, opts)}
+	for _, exec := range Executions(result.Outliers) {
+		if hasTag(exec, opts.Tag) {
+			continue
+		}
+		result.Untagged = append(result.Untagged, exec.ID)
+	}
+	if dryRun {
+		return result, nil
+	}
+
+	for _, id := range result.Untagged {
+		//search results may be incomplete, update the full execution
+		exec, err := c.GetTestExecution(id)
+		if err != nil {
+			return result, errors.Wrapf(err, "Failed to tag outlier execution %d", id)
+		}
+		if hasTag(exec, opts.Tag) {
+			continue
+		}
+		exec.Tags = append(exec.Tags, apis.Tag{Name: opts.Tag})
+		if _, err := c.UpdateTestExecution(exec); err != nil {
+			return result, errors.Wrapf(err, "Failed to tag outlier execution %d", id)
+		}
+		result.Tagged = append(result.Tagged, id)
+	}
+	return result, nil
+}
+
+// Find returns outlier values of the executions. Each metric is checked separately for every
+// combination of value parameters. Outliers are ordered by metric, parameters and score descending.
+func Find(executions []apis.TestExecution, opts Options) []Outlier {
+	opts.setDefaults()
+	type historyKey struct {
+		metric string
+		params string
+	}
+	type point struct {
+		exec   *apis.TestExecution
+		result float64
+	}
+	histories := make(map[historyKey][]point)
+	for i := range executions {
+		for j := range executions[i].Values {
+			v := &executions[i].Values[j]
+			if len(opts.Metrics) > 0 && !contains(opts.Metrics, v.MetricName) {
+				continue
+			}
+			key := historyKey{metric: v.MetricName, params: v.ParametersKey()}
+			histories[key] = append(histories[key], point{exec: &executions[i], result: v.Result})
+		}
+	}
+
+	var outliers []Outlier
+	for key, points := range histories {
+		if len(points) < opts.MinSamples {
+			continue
+		}
+		values := make([]float64, len(points))
+		for i, p := range points {
+			values[i] = p.result
+		}
+		f := newFences(values, opts)
+		for _, p := range points {
+			if score, ok := f.score(p.result); ok {
+				outliers = append(outliers, Outlier{
+					Execution:  p.exec,
+					Metric:     key.metric,
+					Parameters: key.params,
+					Result:     p.result,
+					Median:     f.median,
+					Lower:      f.lower,
+					Upper:      f.upper,
+					Score:      score,
+				})
+			}
+		}
+	}
+	sort.SliceStable(outliers, func(i, j int) bool {
+		if outliers[i].Metric != outliers[j].Metric {
+			return outliers[i].Metric < outliers[j].Metric
+		}
+		if outliers[i].Parameters != outliers[j].Parameters {
+			return outliers[i].Parameters < outliers[j].Parameters
+		}
+		return outliers[i].Score > outliers[j].Score
+	})
+	return outliers
+}
+
+// Executions returns the distinct executions of the outliers in order of their first occurrence
+func Executions(outliers []Outlier) []*apis.TestExecution {
+	seen := make(map[*apis.TestExecution]bool)
+	var executions []*apis.TestExecution
+	for _, o := range outliers {
+		if !seen[o.Execution] {
+			seen[o.Execution] = true
+			executions = append(executions, o.Execution)
+		}
+	}
+	return executions
+}
+
+// fences holds the bounds of regular values of a metric history. Scores are measured from
+// the reference points in units of scale: both are the median for MAD and the quartiles for IQR.
+type fences struct {
+	median   float64
+	lower    float64
+	upper    float64
+	lowerRef float64
+	upperRef float64
+	scale    float64
+}
+
+func newFences(values []float64, opts Options) fences {
+	f := fences{median: stats.Median(values)}
+	switch opts.Method {
+	case IQR:
+		f.lowerRef, f.upperRef = stats.Quantile(values, 0.25), stats.Quantile(values, 0.75)
+		f.scale = f.upperRef - f.lowerRef
+	default:
+		f.lowerRef, f.upperRef = f.median, f.median
+		f.scale = madScale * stats.MedianAbsoluteDeviation(values)
+		if f.scale < madZeroLimit {
+			//more than half of the values are equal, fall back to the mean absolute deviation
+			sum := 0.0
+			for _, v := range values {
+				sum += math.Abs(v - f.median)
+			}
+			f.scale = meanADScale * sum / float64(len(values))
+		}
+	}
+	f.lower = f.lowerRef - opts.Threshold*f.scale
+	f.upper = f.upperRef + opts.Threshold*f.scale
+	return f
+}
+
+// score returns the score of the value and whether it lies beyond the fences
+func (f fences) score(v float64) (float64, bool) {
+	if v >= f.lower && v <= f.upper {
+		return 0, false
+	}
+	if f.scale == 0 {
+		return math.Inf(1), true
+	}
+	if v < f.lower {
+		return (f.lowerRef - v) / f.scale, true
+	}
+	return (v - f.upperRef) / f.scale, true
+}
+
+func hasTag(exec *apis.TestExecution, tag string) bool {
+	for _, t := range exec.Tags {
+		if t.Name == tag {
+			return true
+		}
+	}
+	return false
+}
+
+func contains(list []string, s string) bool {
+	for _, e := range list {
+		if e == s {
+			return true
+		}
+	}
+	return false
+}
+
+func (o *Options) setDefaults() {
+	if o.Threshold <= 0 {
+		o.Threshold = defaultMADThreshold
+		if o.Method == IQR {
+			o.Threshold = defaultIQRThreshold
+		}
+	}
+	if o.MinSamples <= 0 {
+		o.MinSamples = defaultMinSamples
+	}
+	if o.Tag == "" {
+		o.Tag = DefaultTag
+	}
+}
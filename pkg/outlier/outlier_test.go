@@ -0,0 +1,139 @@
+package outlier
+
+import (
+	"fmt"
+	"math"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/mgencur/go-perfrepoclient/pkg/apis"
+	"github.com/mgencur/go-perfrepoclient/pkg/client"
+)
+
+func history(metric string, results ...float64) []apis.TestExecution {
+	execs := make([]apis.TestExecution, len(results))
+	for i, r := range results {
+		execs[i] = apis.TestExecution{ID: int64(i + 1), Values: []apis.Value{{MetricName: metric, Result: r}}}
+	}
+	return execs
+}
+
+func TestFind(t *testing.T) {
+	type found struct {
+		result float64
+		score  float64
+	}
+	cases := []struct {
+		name     string
+		results  []float64
+		opts     Options
+		outliers []found
+	}{
+		//median 12.5, MAD 1.5 scaled to 2.2239
+		{"MAD", []float64{10, 11, 12, 13, 14, 50}, Options{}, []found{{50, 37.5 / (1.4826 * 1.5)}}},
+		//MAD is zero, mean absolute deviation 10/7 scaled to 1.7904
+		{"MAD fallback", []float64{10, 10, 10, 10, 10, 10, 20}, Options{}, []found{{20, 10 / (1.2533 * 10 / 7)}}},
+		{"equal values", []float64{10, 10, 10, 10, 10}, Options{}, nil},
+		//quartiles 3 and 7, fences -3 and 13, ordered by score
+		{"IQR", []float64{-10, 2, 3, 4, 5, 6, 7, 8, 30}, Options{Method: IQR}, []found{{30, 23.0 / 4}, {-10, 13.0 / 4}}},
+		{"IQR threshold", []float64{-10, 2, 3, 4, 5, 6, 7, 8, 30}, Options{Method: IQR, Threshold: 5}, []found{{30, 23.0 / 4}}},
+		{"too few samples", []float64{10, 11, 12, 50}, Options{}, nil},
+		{"other metric", []float64{10, 11, 12, 13, 14, 50}, Options{Metrics: []string{"other"}}, nil},
+	}
+	for _, c := range cases {
+		outliers := Find(history("m", c.results...), c.opts)
+		if len(outliers) != len(c.outliers) {
+			t.Errorf("%s: expected %v, got %+v", c.name, c.outliers, outliers)
+			continue
+		}
+		for i, e := range c.outliers {
+			o := outliers[i]
+			if o.Result != e.result || math.Abs(o.Score-e.score) > 1e-3 || o.Metric != "m" {
+				t.Errorf("%s: expected %v, got %+v", c.name, e, o)
+			}
+			if o.Result >= o.Lower && o.Result <= o.Upper {
+				t.Errorf("%s: outlier %v within fences [%v, %v]", c.name, o.Result, o.Lower, o.Upper)
+			}
+		}
+	}
+}
+
+func TestFindMultiValue(t *testing.T) {
+	var executions []apis.TestExecution
+	for i, r := range []float64{10, 11, 12, 13, 14, 50} {
+		executions = append(executions, apis.TestExecution{ID: int64(i + 1), Values: []apis.Value{
+			{MetricName: "m", Result: r, Parameters: []apis.ValueParameter{{Name: "client", Value: "1"}}},
+			{MetricName: "m", Result: 100 + r, Parameters: []apis.ValueParameter{{Name: "client", Value: "2"}}},
+		}})
+	}
+	//the last execution is an outlier for both clients
+	outliers := Find(executions, Options{})
+	if len(outliers) != 2 || outliers[0].Parameters != "client=1" || outliers[1].Parameters != "client=2" {
+		t.Fatalf("Unexpected outliers %+v", outliers)
+	}
+	if execs := Executions(outliers); len(execs) != 1 || execs[0].ID != 6 {
+		t.Errorf("Expected the last execution once, got %+v", execs)
+	}
+}
+
+// executionXML returns an execution with a value of metric m, tagged when tag isn't empty
+func executionXML(id int64, result float64, tag string) string {
+	tags := ""
+	if tag != "" {
+		tags = fmt.Sprintf(`<tags><tag name="%s"/></tags>`, tag)
+	}
+	return fmt.Sprintf(`<testExecution id="%d" name="e%d" testId="1" testUid="t" started="2026-01-01T00:00:00-00:00">%s
+<values><value metricName="m" result="%g"/></values></testExecution>`, id, id, tags, result)
+}
+
+func TestFlag(t *testing.T) {
+	results := []float64{10, 11, 12, 13, 14, 50, 60, 70}
+	var search strings.Builder
+	search.WriteString("<testExecutions>")
+	for i, r := range results {
+		search.WriteString(executionXML(int64(i+1), r, ""))
+	}
+	search.WriteString("</testExecutions>")
+	updates := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.Method + " " + r.URL.Path {
+		case "POST /rest/testExecution/search":
+			w.Write([]byte(search.String()))
+		case "GET /rest/testExecution/8":
+			w.Write([]byte(executionXML(8, 70, "")))
+		case "GET /rest/testExecution/6":
+			w.Write([]byte(executionXML(6, 50, "")))
+		case "GET /rest/testExecution/7":
+			//tagged since the search
+			w.Write([]byte(executionXML(7, 60, DefaultTag)))
+		case "POST /rest/testExecution/update/8":
+			updates++
+			w.WriteHeader(http.StatusCreated)
+			w.Write([]byte("8"))
+		default:
+			w.WriteHeader(http.StatusInternalServerError)
+		}
+	}))
+	defer server.Close()
+	c := client.NewClient(server.URL, "user", "pass")
+
+	result, err := Flag(c, &apis.TestExecutionSearch{TestUID: "t"}, Options{}, true)
+	if err != nil {
+		t.Fatal("Failed to find outliers", err)
+	}
+	//the outliers are ordered by score
+	if len(result.Outliers) != 3 || fmt.Sprint(result.Untagged) != "[8 7 6]" || len(result.Tagged) != 0 || updates != 0 {
+		t.Fatalf("Unexpected dry run result %+v", result)
+	}
+
+	//the update of execution 6 fails
+	result, err = Flag(c, &apis.TestExecutionSearch{TestUID: "t"}, Options{}, false)
+	if err == nil || !strings.Contains(err.Error(), "execution 6") {
+		t.Errorf("Expected an error for execution 6, got %v", err)
+	}
+	if result == nil || fmt.Sprint(result.Tagged) != "[8]" || updates != 1 {
+		t.Errorf("Expected only execution 8 to be tagged, got %+v", result)
+	}
+}
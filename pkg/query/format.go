@@ -0,0 +1,98 @@
+package query
+
+import (
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/mgencur/go-perfrepoclient/pkg/apis"
+)
+
+// Format converts search criteria to a query which Parse turns back to equal criteria
+func Format(search *apis.TestExecutionSearch) string {
+	if search == nil {
+		return ""
+	}
+	var terms []string
+	add := func(key, value string) {
+		terms = append(terms, key+quote(value))
+	}
+	if search.TestUID != "" {
+		add("test:", search.TestUID)
+	}
+	if search.TestName != "" {
+		add("name:", search.TestName)
+	}
+	if search.IDS != nil && len(*search.IDS) > 0 {
+		ids := make([]string, 0, len(*search.IDS))
+		for _, id := range *search.IDS {
+			ids = append(ids, strconv.FormatInt(id, 10))
+		}
+		add("id:", strings.Join(ids, ","))
+	}
+	for _, tag := range strings.Fields(search.Tags) {
+		if strings.HasPrefix(tag, "-") {
+			add("-tag:", strings.TrimPrefix(tag, "-"))
+		} else {
+			add("tag:", tag)
+		}
+	}
+	for _, p := range search.Parameters {
+		terms = append(terms, "param."+quoteParameter(p.Name)+"="+quoteParameter(p.Value))
+	}
+	if search.ExecutedAfter != nil {
+		add("after:", formatTime(search.ExecutedAfter.Time))
+	}
+	if search.ExecutedBefore != nil {
+		add("before:", formatTime(search.ExecutedBefore.Time))
+	}
+	switch search.GroupFilter {
+	case apis.MyGroupFilter:
+		add("group:", "my")
+	case apis.AllGroupFilter:
+		add("group:", "all")
+	}
+	if search.OrderBy != apis.UnknownOrderBy {
+		order := strings.ToLower(search.OrderBy.String())
+		if search.OrderBy == apis.ParameterAscOrderBy || search.OrderBy == apis.ParameterDescOrderBy {
+			order += ":" + search.OrderByParameter
+		}
+		add("order:", order)
+	}
+	if search.LabelParameter != "" {
+		add("label:", search.LabelParameter)
+	}
+	if search.LimitFrom > 0 {
+		add("offset:", strconv.Itoa(search.LimitFrom))
+	}
+	if search.HowMany > 0 {
+		add("limit:", strconv.Itoa(search.HowMany))
+	}
+	return strings.Join(terms, " ")
+}
+
+func formatTime(t time.Time) string {
+	if t.Equal(t.Truncate(24*time.Hour)) && t.Location() == time.UTC {
+		return t.Format(dateFormat)
+	}
+	return t.Format(time.RFC3339Nano)
+}
+
+// quote encloses values which wouldn't be parsed back as they are in double quotes
+func quote(value string) string {
+	return quoteWith(value, " \t\r\n\"\\")
+}
+
+// quoteParameter quotes parameter names and values also when they contain the separators of
+// the param.<name>=<value> term, e.g. a name containing = would otherwise end early
+func quoteParameter(value string) string {
+	return quoteWith(value, " \t\r\n\"\\=:")
+}
+
+func quoteWith(value, special string) string {
+	if value != "" && !strings.ContainsAny(value, special) {
+		return value
+	}
+	r := strings.NewReplacer(`\`, `\\`, `"`, `\"`)
+	return `"` + r.Replace(value) + `"`
+}
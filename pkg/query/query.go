@@ -0,0 +1,290 @@
+// Package query implements a compact text syntax for test execution searches, e.g.
+//
+//	test:order-service tag:nightly -tag:invalid param.git_branch=main after:2026-01-01 order:date_desc limit:50
+//
+// Supported terms are:
+//
+//	test:<uid>                  executions of the test with the given UID
+//	name:<name>                 executions of tests with the given name
+//	id:<id>[,<id>...]           executions with the given IDs
+//	tag:<tag>, -tag:<tag>       executions with/without the tag
+//	param.<name>=<value>        executions with the parameter value, the name may be quoted as well
+//	after:<time>, before:<time> executions started after/before the time (2006-01-02 or RFC 3339)
+//	group:my|all                executions of my groups or all groups
+//	order:<order>               e.g. date_desc, name_asc or parameter_asc:<parameter>
+//	label:<parameter>           parameter used as a label of the executions
+//	offset:<n>, limit:<n>       paging of the results
+//
+// Values containing whitespace can be enclosed in double quotes, quotes and backslashes
+// inside them are escaped by a backslash.
+package query
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+
+	"github.com/mgencur/go-perfrepoclient/pkg/apis"
+)
+
+const dateFormat = "2006-01-02"
+
+// ParseError describes a syntax error in a query
+type ParseError struct {
+	Pos int    // byte offset of the offending term in the query
+	Msg string // description of the problem
+}
+
+func (e *ParseError) Error() string {
+	return fmt.Sprintf("position %d: %s", e.Pos, e.Msg)
+}
+
+type term struct {
+	pos     int
+	negated bool
+	key     string
+	value   string
+	param   bool // key is a parameter name of a param.<name>=<value> term
+}
+
+// Parse converts the query to search criteria. Returns a *ParseError pointing to the offending
+// term when the query is malformed.
+func Parse(input string) (*apis.TestExecutionSearch, error) {
+	terms, err := tokenize(input)
+	if err != nil {
+		return nil, err
+	}
+	b := apis.NewSearch()
+	var ids []int64
+	var after, before time.Time
+	offset, limit, beforePos := 0, 0, 0
+	for _, t := range terms {
+		if t.negated && t.key != "tag" {
+			return nil, &ParseError{t.pos, fmt.Sprintf("Term %s can't be negated", t.key)}
+		}
+		if t.value == "" && !t.param {
+			return nil, &ParseError{t.pos, fmt.Sprintf("Missing value for %s", t.key)}
+		}
+		switch {
+		case t.param:
+			b.WithParameter(t.key, t.value)
+		case t.key == "test":
+			b.ForTestUID(t.value)
+		case t.key == "name":
+			b.ForTestName(t.value)
+		case t.key == "id":
+			for _, s := range strings.Split(t.value, ",") {
+				id, err := strconv.ParseInt(s, 10, 64)
+				if err != nil {
+					return nil, &ParseError{t.pos, fmt.Sprintf("Invalid id %q", s)}
+				}
+				ids = append(ids, id)
+			}
+		case t.key == "tag":
+			if strings.IndexFunc(t.value, unicode.IsSpace) >= 0 || strings.HasPrefix(t.value, "-") {
+				return nil, &ParseError{t.pos, fmt.Sprintf("Invalid tag %q", t.value)}
+			}
+			if t.negated {
+				b.WithoutTags(t.value)
+			} else {
+				b.WithTags(t.value)
+			}
+		case t.key == "after" || t.key == "before":
+			tm, err := parseTime(t.value)
+			if err != nil {
+				return nil, &ParseError{t.pos, fmt.Sprintf("Invalid time %q, expected %s or RFC 3339", t.value, dateFormat)}
+			}
+			if t.key == "after" {
+				after = tm
+				b.ExecutedAfter(tm)
+			} else {
+				before, beforePos = tm, t.pos
+				b.ExecutedBefore(tm)
+			}
+		case t.key == "group":
+			filter, err := apis.ParseGroupFilter(strings.ToUpper(t.value) + "_GROUPS")
+			if err != nil {
+				return nil, &ParseError{t.pos, fmt.Sprintf("Invalid group %q, expected my or all", t.value)}
+			}
+			b.InGroups(filter)
+		case t.key == "order":
+			if err := parseOrder(b, t.value); err != nil {
+				return nil, &ParseError{t.pos, err.Error()}
+			}
+		case t.key == "label":
+			b.LabelParameter(t.value)
+		case t.key == "offset":
+			offset, err = strconv.Atoi(t.value)
+			if err != nil || offset < 0 {
+				return nil, &ParseError{t.pos, fmt.Sprintf("Invalid offset %q", t.value)}
+			}
+		case t.key == "limit":
+			limit, err = strconv.Atoi(t.value)
+			if err != nil || limit <= 0 {
+				return nil, &ParseError{t.pos, fmt.Sprintf("Invalid limit %q", t.value)}
+			}
+		default:
+			return nil, &ParseError{t.pos, fmt.Sprintf("Unknown term %s", t.key)}
+		}
+		//the builder validates values as well, point to the term it rejected
+		if err := b.Err(); err != nil {
+			return nil, &ParseError{t.pos, err.Error()}
+		}
+	}
+	if len(ids) > 0 {
+		b.ForIDs(ids...)
+	}
+	if offset > 0 && limit == 0 {
+		return nil, &ParseError{len(input), "Offset requires a limit"}
+	}
+	if limit > 0 {
+		b.Page(offset, limit)
+	}
+	if !after.IsZero() && !before.IsZero() && !after.Before(before) {
+		return nil, &ParseError{beforePos, "Time of before must be later than time of after"}
+	}
+	//problems of single terms are reported above, the rest concerns the query as a whole
+	search, err := b.Build()
+	if err != nil {
+		return nil, &ParseError{len(input), err.Error()}
+	}
+	return search, nil
+}
+
+func parseTime(value string) (time.Time, error) {
+	if t, err := time.Parse(dateFormat, value); err == nil {
+		return t, nil
+	}
+	return time.Parse(time.RFC3339, value)
+}
+
+func parseOrder(b *apis.SearchBuilder, value string) error {
+	name := value
+	param := ""
+	if i := strings.Index(value, ":"); i >= 0 {
+		name, param = value[:i], value[i+1:]
+	}
+	order, err := apis.ParseOrderBy(strings.ToUpper(name))
+	if err != nil || order == apis.UnknownOrderBy {
+		return fmt.Errorf("Invalid order %q", name)
+	}
+	switch order {
+	case apis.ParameterAscOrderBy, apis.ParameterDescOrderBy:
+		if param == "" {
+			return fmt.Errorf("Order %s requires a parameter, e.g. %s:git_branch", name, name)
+		}
+		b.OrderByParameter(param, order == apis.ParameterDescOrderBy)
+	default:
+		if param != "" {
+			return fmt.Errorf("Order %s doesn't take a parameter", name)
+		}
+		b.OrderBy(order)
+	}
+	return nil
+}
+
+// tokenize splits the query into terms separated by whitespace
+func tokenize(input string) ([]term, error) {
+	var terms []term
+	i := 0
+	for i < len(input) {
+		if isSpace(input[i]) {
+			i++
+			continue
+		}
+		t := term{pos: i}
+		if input[i] == '-' {
+			t.negated = true
+			i++
+		}
+		start := i
+		for i < len(input) && input[i] != ':' && input[i] != '=' && input[i] != '"' && !isSpace(input[i]) {
+			i++
+		}
+		t.key = input[start:i]
+		if t.key == "" {
+			return nil, &ParseError{t.pos, "Missing term name"}
+		}
+		if strings.HasPrefix(t.key, "param.") {
+			t.key = strings.TrimPrefix(t.key, "param.")
+			t.param = true
+			if t.key == "" && i < len(input) && input[i] == '"' {
+				name, next, err := readQuoted(input, i)
+				if err != nil {
+					return nil, err
+				}
+				t.key, i = name, next
+			}
+			if t.key == "" {
+				return nil, &ParseError{t.pos, "Missing parameter name"}
+			}
+			if i >= len(input) || input[i] != '=' {
+				return nil, &ParseError{i, fmt.Sprintf("Expected = after parameter %s", t.key)}
+			}
+		} else if i >= len(input) || input[i] != ':' {
+			return nil, &ParseError{i, fmt.Sprintf("Expected : after %s", t.key)}
+		}
+		i++
+		value, next, err := readValue(input, i)
+		if err != nil {
+			return nil, err
+		}
+		t.value = value
+		i = next
+		terms = append(terms, t)
+	}
+	return terms, nil
+}
+
+// readValue reads a plain or double-quoted value starting at position i. Returns the value
+// and the position after it.
+func readValue(input string, i int) (string, int, error) {
+	if i >= len(input) || input[i] != '"' {
+		start := i
+		for i < len(input) && !isSpace(input[i]) {
+			if input[i] == '"' {
+				return "", 0, &ParseError{i, "Unexpected quote inside value"}
+			}
+			i++
+		}
+		return input[start:i], i, nil
+	}
+	value, i, err := readQuoted(input, i)
+	if err != nil {
+		return "", 0, err
+	}
+	if i < len(input) && !isSpace(input[i]) {
+		return "", 0, &ParseError{i, "Expected whitespace after closing quote"}
+	}
+	return value, i, nil
+}
+
+// readQuoted reads a double-quoted string starting at position i. Returns the unescaped string
+// and the position after the closing quote.
+func readQuoted(input string, i int) (string, int, error) {
+	open := i
+	i++
+	var b strings.Builder
+	for i < len(input) {
+		switch input[i] {
+		case '\\':
+			if i+1 >= len(input) {
+				return "", 0, &ParseError{i, "Unfinished escape sequence"}
+			}
+			b.WriteByte(input[i+1])
+			i += 2
+		case '"':
+			return b.String(), i + 1, nil
+		default:
+			b.WriteByte(input[i])
+			i++
+		}
+	}
+	return "", 0, &ParseError{open, "Missing closing quote"}
+}
+
+func isSpace(c byte) bool {
+	return c == ' ' || c == '\t' || c == '\n' || c == '\r'
+}
@@ -0,0 +1,86 @@
+package query
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/mgencur/go-perfrepoclient/pkg/apis"
+)
+
+func TestParse(t *testing.T) {
+	search, err := Parse(`test:order-service tag:nightly -tag:invalid param.git_branch=main ` +
+		`param.jvm="-Xmx2g -Xms2g" after:2026-01-01 order:date_desc limit:50`)
+	if err != nil {
+		t.Fatal("Failed to parse query", err)
+	}
+	expected := &apis.TestExecutionSearch{
+		TestUID: "order-service",
+		Tags:    "nightly -invalid",
+		Parameters: []apis.CriteriaParameter{
+			{Name: "git_branch", Value: "main"},
+			{Name: "jvm", Value: "-Xmx2g -Xms2g"},
+		},
+		ExecutedAfter: &apis.JaxbTime{Time: time.Date(2026, time.January, 1, 0, 0, 0, 0, time.UTC)},
+		OrderBy:       apis.DateDescOrderBy,
+		HowMany:       50,
+	}
+	if !reflect.DeepEqual(search, expected) {
+		t.Fatalf("Expected %+v, got %+v", expected, search)
+	}
+}
+
+func TestFormatRoundTrip(t *testing.T) {
+	queries := []string{
+		`test:order-service tag:nightly -tag:invalid param.git_branch=main after:2026-01-01 order:date_desc limit:50`,
+		`name:"Order \"service\"" id:1,2,3 before:2026-01-01T10:30:00+02:00 group:all order:parameter_asc:git_commit label:git_commit offset:100 limit:50`,
+		`param.empty="" order:name_asc`,
+		`param."a=b"="c:d" param."with space"=x param."say \"hi\""=y`,
+	}
+	for _, q := range queries {
+		search, err := Parse(q)
+		if err != nil {
+			t.Fatalf("Failed to parse %q: %v", q, err)
+		}
+		formatted := Format(search)
+		if formatted != q {
+			t.Errorf("Expected %q, got %q", q, formatted)
+		}
+		reparsed, err := Parse(formatted)
+		if err != nil || !reflect.DeepEqual(search, reparsed) {
+			t.Errorf("Search %+v not equal after round trip %+v (error %v)", search, reparsed, err)
+		}
+	}
+}
+
+func TestParseErrors(t *testing.T) {
+	cases := []struct {
+		query string
+		pos   int
+	}{
+		{"tag:a foo:b", 6},
+		{"tag:a -test:b", 6},
+		{"tag:a test", 10},
+		{"tag:a order:parameter_asc", 6},
+		{"tag:a order:date_desc:x", 6},
+		{`tag:a name:"unfinished`, 11},
+		{"after:2026-02-01 before:2026-01-01", 17},
+		{"limit:-1", 0},
+		{"param.=x", 0},
+		{"id:1,x", 0},
+		{"offset:10", 9},
+		{`param."a=b`, 6},
+		{`param."a"x`, 9},
+	}
+	for _, c := range cases {
+		_, err := Parse(c.query)
+		perr, ok := err.(*ParseError)
+		if !ok {
+			t.Errorf("Expected ParseError for %q, got %v", c.query, err)
+			continue
+		}
+		if perr.Pos != c.pos {
+			t.Errorf("Expected error at position %d for %q, got %v", c.pos, c.query, perr)
+		}
+	}
+}
@@ -0,0 +1,199 @@
+// Package regression detects statistically significant changes of metrics between a set of
+// baseline executions and a set of candidate executions.
+package regression
+
+import (
+	"math/rand"
+	"sort"
+
+	"github.com/pkg/errors"
+
+	"github.com/mgencur/go-perfrepoclient/pkg/apis"
+	"github.com/mgencur/go-perfrepoclient/pkg/client"
+	"github.com/mgencur/go-perfrepoclient/pkg/compare"
+	"github.com/mgencur/go-perfrepoclient/pkg/stats"
+)
+
+const (
+	defaultAlpha      = 0.05
+	defaultConfidence = 0.95
+	defaultResamples  = 2000
+	defaultMinSamples = 3
+)
+
+// SignificanceTest selects the hypothesis test deciding about significance of a change
+type SignificanceTest int
+
+// enumerate values for SignificanceTest
+const (
+	MannWhitneyTest SignificanceTest = iota
+	WelchTest
+)
+
+// Options configures the detection. Zero values select defaults.
+type Options struct {
+	Alpha      float64          // significance level, 0.05 by default
+	Test       SignificanceTest // test deciding about significance, Mann-Whitney U by default
+	MinChange  float64          // minimal relative change of the median to report, e.g. 0.02 for 2%
+	MinSamples int              // minimal number of values in each set, 3 by default
+	Confidence float64          // confidence of the bootstrap interval, 0.95 by default
+	Resamples  int              // number of bootstrap resamples, 2000 by default
+	Seed       int64            // seed of the bootstrap so that results are reproducible
+	// Comparators by metric name, take precedence over comparators of the values
+	Comparators map[string]apis.Comparator
+}
+
+// MetricResult holds the outcome of the detection for a single metric value
+type MetricResult struct {
+	Metric           string
+	Parameters       string // value parameters of multi-value metrics, see apis.Value.ParametersKey
+	Comparator       apis.Comparator
+	BaselineSamples  int
+	CandidateSamples int
+	BaselineMedian   float64
+	CandidateMedian  float64
+	Change           float64 // relative change of the median
+	Welch            stats.TestResult
+	MannWhitney      stats.TestResult
+	MedianDifference stats.Interval // bootstrap interval of CandidateMedian - BaselineMedian
+	CohensD          float64        // standardized difference of means
+	RankBiserial     float64        // effect size of the Mann-Whitney U test
+	Verdict          compare.Verdict
+	Insufficient     bool // too few samples, the verdict is Unchanged
+}
+
+// DetectAgainstSearch detects regressions of the candidate executions against the baseline executions
+// found by the criteria, e.g. last N executions tagged "baseline". Candidate executions are excluded
+// from the baseline.
+func DetectAgainstSearch(c *client.PerfRepoClient, baseline *apis.TestExecutionSearch,
+	candidate []apis.TestExecution, opts Options) ([]MetricResult, error) {
+	found, err := c.SearchTestExecutions(baseline)
+	if err != nil {
+		return nil, errors.Wrap(err, "Failed to fetch baseline executions")
+	}
+	candidateIDs := make(map[int64]bool)
+	for _, exec := range candidate {
+		candidateIDs[exec.ID] = true
+	}
+	var baselineExecs []apis.TestExecution
+	for _, exec := range found {
+		if exec.ID == 0 || !candidateIDs[exec.ID] {
+			baselineExecs = append(baselineExecs, exec)
+		}
+	}
+	return Detect(baselineExecs, candidate, opts), nil
+}
+
+// Detect compares values of each metric in the candidate executions to its values in the baseline
+// executions. Values of multi-value metrics are compared separately per their value parameters.
+// A change is reported as a regression or an improvement, respecting the metric comparator, when
+// the selected test is significant and the median changed at least by MinChange.
+// Results are ordered by metric name and parameters.
+func Detect(baseline, candidate []apis.TestExecution, opts Options) []MetricResult {
+	opts.setDefaults()
+	baseSamples := collect(baseline)
+	candSamples := collect(candidate)
+
+	var results []MetricResult
+	for key, cand := range candSamples {
+		base, ok := baseSamples[key]
+		if !ok {
+			continue
+		}
+		comparator := base.comparator
+		if comparator == apis.UnknownComparator {
+			comparator = cand.comparator
+		}
+		if c, ok := opts.Comparators[key.metric]; ok && c != apis.UnknownComparator {
+			comparator = c
+		}
+		results = append(results, analyze(key, comparator, base.values, cand.values, opts))
+	}
+	sort.Slice(results, func(i, j int) bool {
+		if results[i].Metric != results[j].Metric {
+			return results[i].Metric < results[j].Metric
+		}
+		return results[i].Parameters < results[j].Parameters
+	})
+	return results
+}
+
+func analyze(key sampleKey, comparator apis.Comparator, base, cand []float64, opts Options) MetricResult {
+	r := MetricResult{
+		Metric:           key.metric,
+		Parameters:       key.params,
+		Comparator:       comparator,
+		BaselineSamples:  len(base),
+		CandidateSamples: len(cand),
+		BaselineMedian:   stats.Median(base),
+		CandidateMedian:  stats.Median(cand),
+		Verdict:          compare.Unchanged,
+	}
+	r.Change = compare.RelativeChange(r.BaselineMedian, r.CandidateMedian)
+	if len(base) < opts.MinSamples || len(cand) < opts.MinSamples {
+		r.Insufficient = true
+		return r
+	}
+	r.Welch = stats.WelchTTest(base, cand)
+	r.MannWhitney = stats.MannWhitneyU(base, cand)
+	r.MedianDifference = stats.BootstrapMedianDifference(base, cand, opts.Resamples, opts.Confidence,
+		rand.New(rand.NewSource(opts.Seed)))
+	r.CohensD = stats.CohensD(base, cand)
+	r.RankBiserial = stats.RankBiserial(base, cand)
+
+	p := r.MannWhitney.PValue
+	if opts.Test == WelchTest {
+		p = r.Welch.PValue
+	}
+	if p < opts.Alpha {
+		r.Verdict = compare.Judge(comparator, r.CandidateMedian-r.BaselineMedian, r.Change,
+			compare.Threshold{Relative: opts.MinChange})
+	}
+	return r
+}
+
+func (o *Options) setDefaults() {
+	if o.Alpha <= 0 {
+		o.Alpha = defaultAlpha
+	}
+	if o.Confidence <= 0 {
+		o.Confidence = defaultConfidence
+	}
+	if o.Resamples <= 0 {
+		o.Resamples = defaultResamples
+	}
+	if o.MinSamples <= 0 {
+		o.MinSamples = defaultMinSamples
+	}
+}
+
+type sampleKey struct {
+	metric string
+	params string
+}
+
+type sample struct {
+	comparator apis.Comparator
+	values     []float64
+}
+
+// collect groups values of the executions by metric and value parameters
+func collect(executions []apis.TestExecution) map[sampleKey]*sample {
+	samples := make(map[sampleKey]*sample)
+	for i := range executions {
+		for j := range executions[i].Values {
+			v := &executions[i].Values[j]
+			key := sampleKey{metric: v.MetricName, params: v.ParametersKey()}
+			s, ok := samples[key]
+			if !ok {
+				s = &sample{}
+				samples[key] = s
+			}
+			if s.comparator == apis.UnknownComparator {
+				s.comparator = v.MetricComparator
+			}
+			s.values = append(s.values, v.Result)
+		}
+	}
+	return samples
+}
@@ -0,0 +1,85 @@
+package regression
+
+import (
+	"testing"
+
+	"github.com/mgencur/go-perfrepoclient/pkg/apis"
+	"github.com/mgencur/go-perfrepoclient/pkg/compare"
+)
+
+// executions creates an execution for each result with a value of the metric
+func executions(metric string, comparator apis.Comparator, results ...float64) []apis.TestExecution {
+	execs := make([]apis.TestExecution, len(results))
+	for i, r := range results {
+		execs[i].Values = []apis.Value{{MetricName: metric, MetricComparator: comparator, Result: r}}
+	}
+	return execs
+}
+
+func TestDetect(t *testing.T) {
+	stable := []float64{100, 101, 99, 100, 102, 98}
+	slower := []float64{120, 121, 119, 122, 118, 120}
+	cases := []struct {
+		name         string
+		comparator   apis.Comparator
+		baseline     []float64
+		candidate    []float64
+		opts         Options
+		verdict      compare.Verdict
+		insufficient bool
+	}{
+		{"LB increase", apis.LBComparator, stable, slower, Options{}, compare.Regressed, false},
+		{"HB increase", apis.HBComparator, stable, slower, Options{}, compare.Improved, false},
+		{"LB decrease", apis.LBComparator, slower, stable, Options{}, compare.Improved, false},
+		{"Welch", apis.LBComparator, stable, slower, Options{Test: WelchTest}, compare.Regressed, false},
+		{"unknown comparator", apis.UnknownComparator, stable, slower, Options{}, compare.Changed, false},
+		{"comparator override", apis.LBComparator, stable, slower,
+			Options{Comparators: map[string]apis.Comparator{"m": apis.HBComparator}}, compare.Improved, false},
+		{"noise", apis.LBComparator, stable, []float64{101, 99, 100, 102, 98, 100}, Options{}, compare.Unchanged, false},
+		{"below MinChange", apis.LBComparator, stable, slower, Options{MinChange: 0.5}, compare.Unchanged, false},
+		{"too few samples", apis.LBComparator, stable, slower[:2], Options{}, compare.Unchanged, true},
+	}
+	for _, c := range cases {
+		results := Detect(executions("m", c.comparator, c.baseline...),
+			executions("m", c.comparator, c.candidate...), c.opts)
+		if len(results) != 1 {
+			t.Errorf("%s: expected 1 result, got %+v", c.name, results)
+			continue
+		}
+		r := results[0]
+		if r.Verdict != c.verdict || r.Insufficient != c.insufficient {
+			t.Errorf("%s: expected %v (insufficient %v), got %v (insufficient %v)", c.name, c.verdict,
+				c.insufficient, r.Verdict, r.Insufficient)
+		}
+	}
+}
+
+func TestDetectMultiValue(t *testing.T) {
+	execution := func(client1, client2 float64) apis.TestExecution {
+		return apis.TestExecution{Values: []apis.Value{
+			{MetricName: "m", Result: client1, Parameters: []apis.ValueParameter{{Name: "client", Value: "1"}}},
+			{MetricName: "m", Result: client2, Parameters: []apis.ValueParameter{{Name: "client", Value: "2"}}},
+			{MetricName: "constant", Result: 1},
+		}}
+	}
+	var baseline, candidate []apis.TestExecution
+	for i := 0; i < 5; i++ {
+		baseline = append(baseline, execution(10+float64(i), 20+float64(i)))
+		candidate = append(candidate, execution(10+float64(i), 40+float64(i)))
+	}
+	results := Detect(baseline, candidate[:4], Options{Comparators: map[string]apis.Comparator{"m": apis.HBComparator}})
+	if len(results) != 3 {
+		t.Fatalf("Expected results for both clients and the constant metric, got %+v", results)
+	}
+	//results are ordered by metric and parameters
+	if results[0].Metric != "constant" || results[0].Verdict != compare.Unchanged {
+		t.Errorf("Unexpected result for constant metric %+v", results[0])
+	}
+	if results[1].Parameters != "client=1" || results[1].Verdict != compare.Unchanged {
+		t.Errorf("Unexpected result for client 1 %+v", results[1])
+	}
+	if results[2].Parameters != "client=2" || results[2].Verdict != compare.Improved ||
+		results[2].BaselineSamples != 5 || results[2].CandidateSamples != 4 {
+		t.Errorf("Unexpected result for client 2 %+v", results[2])
+	}
+}
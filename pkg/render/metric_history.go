@@ -0,0 +1,181 @@
+package render
+
+import (
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+
+	"github.com/mgencur/go-perfrepoclient/pkg/apis"
+	"github.com/mgencur/go-perfrepoclient/pkg/client"
+)
+
+const (
+	defaultWidth  = 800
+	defaultHeight = 300
+	dateFormat    = "2006-01-02 15:04"
+)
+
+// Options configures the rendered page
+type Options struct {
+	Title         string   // page title, the test name is used when empty
+	Width         int      // width of a single chart in pixels
+	Height        int      // height of a single chart in pixels
+	KeyParameters []string // execution parameters shown on hover, all parameters when empty
+}
+
+// Chart holds the history of a single metric
+type Chart struct {
+	Metric      string
+	Description string
+	Series      []Series
+}
+
+// Series holds values of a metric sharing the same value parameters
+type Series struct {
+	Name   string // value parameters in form name=value, the metric name when there are none
+	Points []Point
+}
+
+// Point is a single metric value of a test execution
+type Point struct {
+	Started   time.Time
+	Result    float64
+	Execution *apis.TestExecution
+}
+
+// MetricHistory fetches test executions of the test matching the criteria and writes a
+// self-contained HTML page with a chart for each of the metrics to w. All metrics of the test
+// are rendered when metrics is empty. The criteria are restricted to the given test.
+func MetricHistory(c *client.PerfRepoClient, w io.Writer, testID int64, metrics []string,
+	criteria *apis.TestExecutionSearch, opts Options) error {
+	test, err := c.GetTest(testID)
+	if err != nil {
+		return errors.Wrap(err, "Failed to render metric history")
+	}
+	search := apis.TestExecutionSearch{}
+	if criteria != nil {
+		search = *criteria
+	}
+	search.TestUID = test.UID
+	executions, err := c.SearchTestExecutions(&search)
+	if err != nil {
+		return errors.Wrap(err, "Failed to render metric history")
+	}
+	if len(metrics) == 0 {
+		for _, m := range test.Metrics {
+			metrics = append(metrics, m.Name)
+		}
+	}
+	return WriteHTML(w, test, Charts(test, metrics, executions), opts)
+}
+
+// Charts builds a chart for each of the metrics from the given executions. Values of a metric
+// are split into series by their value parameters and points are ordered by execution start.
+func Charts(test *apis.Test, metrics []string, executions []apis.TestExecution) []Chart {
+	sorted := make([]*apis.TestExecution, 0, len(executions))
+	for i := range executions {
+		if executions[i].Started != nil {
+			sorted = append(sorted, &executions[i])
+		}
+	}
+	sort.SliceStable(sorted, func(i, j int) bool {
+		return sorted[i].Started.Before(sorted[j].Started.Time)
+	})
+
+	charts := make([]Chart, 0, len(metrics))
+	for _, metric := range metrics {
+		chart := Chart{Metric: metric}
+		for _, m := range test.Metrics {
+			if m.Name == metric {
+				chart.Description = m.Description
+			}
+		}
+		seriesIndex := make(map[string]int)
+		for _, exec := range sorted {
+			for _, v := range exec.Values {
+				if v.MetricName != metric {
+					continue
+				}
+				name := seriesName(&v)
+				i, ok := seriesIndex[name]
+				if !ok {
+					i = len(chart.Series)
+					seriesIndex[name] = i
+					chart.Series = append(chart.Series, Series{Name: name})
+				}
+				chart.Series[i].Points = append(chart.Series[i].Points, Point{
+					Started:   exec.Started.Time,
+					Result:    v.Result,
+					Execution: exec,
+				})
+			}
+		}
+		sort.Slice(chart.Series, func(i, j int) bool {
+			return chart.Series[i].Name < chart.Series[j].Name
+		})
+		charts = append(charts, chart)
+	}
+	return charts
+}
+
+// seriesName returns the metric name for single-value metrics and the parameters key otherwise
+func seriesName(v *apis.Value) string {
+	if len(v.Parameters) == 0 {
+		return v.MetricName
+	}
+	return v.ParametersKey()
+}
+
+// WriteHTML writes a self-contained HTML page with an SVG line chart for each chart to w
+func WriteHTML(w io.Writer, test *apis.Test, charts []Chart, opts Options) error {
+	if opts.Width <= 0 {
+		opts.Width = defaultWidth
+	}
+	if opts.Height <= 0 {
+		opts.Height = defaultHeight
+	}
+	title := opts.Title
+	if title == "" && test != nil {
+		title = test.Name
+	}
+	page := pageData{Title: title}
+	if test != nil {
+		page.TestUID = test.UID
+	}
+	for _, c := range charts {
+		page.Charts = append(page.Charts, layoutChart(c, opts))
+	}
+	if err := pageTemplate.Execute(w, page); err != nil {
+		return errors.Wrap(err, "Failed to write HTML")
+	}
+	return nil
+}
+
+func tooltip(p Point, series string, keyParams []string) string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "%s\n%s: %g\n%s", p.Execution.Name, series, p.Result, p.Started.Format(dateFormat))
+	if tags := p.Execution.SortedTags(); len(tags) > 0 {
+		names := make([]string, 0, len(tags))
+		for _, t := range tags {
+			names = append(names, t.Name)
+		}
+		fmt.Fprintf(&b, "\ntags: %s", strings.Join(names, ", "))
+	}
+	if len(keyParams) == 0 {
+		for _, param := range p.Execution.SortedParameters() {
+			fmt.Fprintf(&b, "\n%s=%s", param.Name, param.Value)
+		}
+		return b.String()
+	}
+	params := p.Execution.ParametersMap()
+	for _, name := range keyParams {
+		if value, ok := params[name]; ok {
+			fmt.Fprintf(&b, "\n%s=%s", name, value)
+		}
+	}
+	return b.String()
+}
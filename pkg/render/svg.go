@@ -0,0 +1,172 @@
+package render
+
+import (
+	"fmt"
+	"html/template"
+	"math"
+	"strings"
+	"time"
+)
+
+const (
+	marginLeft   = 70
+	marginRight  = 20
+	marginTop    = 20
+	marginBottom = 40
+	yTicks       = 5
+)
+
+var palette = []string{"#1f77b4", "#ff7f0e", "#2ca02c", "#d62728", "#9467bd",
+	"#8c564b", "#e377c2", "#7f7f7f", "#bcbd22", "#17becf"}
+
+type pageData struct {
+	Title   string
+	TestUID string
+	Charts  []chartLayout
+}
+
+type chartLayout struct {
+	Metric      string
+	Description string
+	Width       int
+	Height      int
+	Left        float64
+	Right       float64
+	Top         float64
+	Bottom      float64
+	YTicks      []tick
+	XTicks      []tick
+	Series      []seriesLayout
+}
+
+type tick struct {
+	Pos   float64
+	Label string
+}
+
+type seriesLayout struct {
+	Name   string
+	Color  string
+	Line   string
+	Points []pointLayout
+}
+
+type pointLayout struct {
+	X, Y    float64
+	Tooltip string
+}
+
+// layoutChart computes positions of all chart elements in SVG coordinates
+func layoutChart(c Chart, opts Options) chartLayout {
+	l := chartLayout{
+		Metric:      c.Metric,
+		Description: c.Description,
+		Width:       opts.Width,
+		Height:      opts.Height,
+		Left:        marginLeft,
+		Right:       float64(opts.Width - marginRight),
+		Top:         marginTop,
+		Bottom:      float64(opts.Height - marginBottom),
+	}
+
+	var minTime, maxTime time.Time
+	minY, maxY := math.Inf(1), math.Inf(-1)
+	for _, s := range c.Series {
+		for _, p := range s.Points {
+			if minTime.IsZero() || p.Started.Before(minTime) {
+				minTime = p.Started
+			}
+			if p.Started.After(maxTime) {
+				maxTime = p.Started
+			}
+			minY = math.Min(minY, p.Result)
+			maxY = math.Max(maxY, p.Result)
+		}
+	}
+	if minTime.IsZero() {
+		return l
+	}
+	if maxY == minY {
+		minY, maxY = minY-1, maxY+1
+	}
+	span := maxTime.Sub(minTime)
+
+	x := func(t time.Time) float64 {
+		if span == 0 {
+			return (l.Left + l.Right) / 2
+		}
+		return l.Left + float64(t.Sub(minTime))/float64(span)*(l.Right-l.Left)
+	}
+	y := func(v float64) float64 {
+		return l.Bottom - (v-minY)/(maxY-minY)*(l.Bottom-l.Top)
+	}
+
+	for i := 0; i < yTicks; i++ {
+		v := minY + float64(i)*(maxY-minY)/float64(yTicks-1)
+		l.YTicks = append(l.YTicks, tick{Pos: y(v), Label: fmt.Sprintf("%.4g", v)})
+	}
+	l.XTicks = append(l.XTicks, tick{Pos: x(minTime), Label: minTime.Format(dateFormat)})
+	if span > 0 {
+		l.XTicks = append(l.XTicks, tick{Pos: x(maxTime), Label: maxTime.Format(dateFormat)})
+	}
+
+	for i, s := range c.Series {
+		sl := seriesLayout{Name: s.Name, Color: palette[i%len(palette)]}
+		coords := make([]string, 0, len(s.Points))
+		for _, p := range s.Points {
+			pl := pointLayout{X: x(p.Started), Y: y(p.Result), Tooltip: tooltip(p, s.Name, opts.KeyParameters)}
+			sl.Points = append(sl.Points, pl)
+			coords = append(coords, fmt.Sprintf("%.1f,%.1f", pl.X, pl.Y))
+		}
+		sl.Line = strings.Join(coords, " ")
+		l.Series = append(l.Series, sl)
+	}
+	return l
+}
+
+var pageTemplate = template.Must(template.New("page").Parse(`<!DOCTYPE html>
+<html>
+<head>
+<meta charset="utf-8">
+<title>{{.Title}}</title>
+<style>
+body { font-family: sans-serif; margin: 2em; }
+h2 { margin-bottom: 0; }
+p.description { color: #555; margin-top: 0.2em; }
+svg text { font-size: 11px; fill: #333; }
+svg .axis { stroke: #333; }
+svg .grid { stroke: #ddd; }
+svg circle:hover { stroke: #000; stroke-width: 2; }
+ul.legend { list-style: none; padding: 0; }
+ul.legend li { display: inline-block; margin-right: 1.5em; }
+ul.legend span { display: inline-block; width: 1em; height: 1em; margin-right: 0.3em; vertical-align: middle; }
+</style>
+</head>
+<body>
+<h1>{{.Title}}</h1>
+{{if .TestUID}}<p>Test: {{.TestUID}}</p>{{end}}
+{{range .Charts}}
+<h2>{{.Metric}}</h2>
+{{if .Description}}<p class="description">{{.Description}}</p>{{end}}
+{{$c := .}}
+<svg xmlns="http://www.w3.org/2000/svg" width="{{.Width}}" height="{{.Height}}" viewBox="0 0 {{.Width}} {{.Height}}">
+{{range .YTicks}}<line class="grid" x1="{{$c.Left}}" x2="{{$c.Right}}" y1="{{.Pos}}" y2="{{.Pos}}"/>
+<text x="{{$c.Left}}" y="{{.Pos}}" dx="-6" dy="4" text-anchor="end">{{.Label}}</text>
+{{end}}
+{{range .XTicks}}<text x="{{.Pos}}" y="{{$c.Bottom}}" dy="18" text-anchor="middle">{{.Label}}</text>
+{{end}}
+<line class="axis" x1="{{.Left}}" x2="{{.Left}}" y1="{{.Top}}" y2="{{.Bottom}}"/>
+<line class="axis" x1="{{.Left}}" x2="{{.Right}}" y1="{{.Bottom}}" y2="{{.Bottom}}"/>
+{{range .Series}}{{$s := .}}
+<polyline fill="none" stroke="{{.Color}}" stroke-width="2" points="{{.Line}}"/>
+{{range .Points}}<circle cx="{{.X}}" cy="{{.Y}}" r="4" fill="{{$s.Color}}"><title>{{.Tooltip}}</title></circle>
+{{end}}{{end}}
+{{if not .Series}}<text x="{{.Left}}" y="{{.Top}}" dx="10" dy="20">No data</text>{{end}}
+</svg>
+<ul class="legend">
+{{range .Series}}<li><span style="background: {{.Color}}"></span>{{.Name}}</li>
+{{end}}</ul>
+{{end}}
+</body>
+</html>
+`))
@@ -0,0 +1,300 @@
+// Package scalability fits Amdahl's law and the Universal Scalability Law to throughput metrics
+// recorded for several levels of concurrency.
+package scalability
+
+import (
+	"math"
+	"sort"
+	"strconv"
+
+	"github.com/pkg/errors"
+
+	"github.com/mgencur/go-perfrepoclient/pkg/apis"
+	"github.com/mgencur/go-perfrepoclient/pkg/compare"
+	"github.com/mgencur/go-perfrepoclient/pkg/stats"
+)
+
+// minPoints is the minimal number of concurrency levels needed for a fit
+const minPoints = 3
+
+// Model is a scalability model
+type Model int
+
+// enumerate values for Model
+const (
+	Amdahl Model = iota // X(N) = λN / (1 + σ(N-1))
+	USL                 // X(N) = λN / (1 + σ(N-1) + κN(N-1))
+)
+
+var modelValues = []string{"Amdahl", "USL"}
+
+func (m *Model) String() string {
+	return modelValues[*m]
+}
+
+// Point is the throughput at a level of concurrency
+type Point struct {
+	N          float64
+	Throughput float64
+}
+
+// Fit holds the fitted coefficients of a model
+type Fit struct {
+	Model    Model
+	Lambda   float64 // throughput of a single unit of concurrency
+	Sigma    float64 // contention coefficient
+	Kappa    float64 // coherency coefficient, always zero for Amdahl
+	RSquared float64 // coefficient of determination
+	// PeakConcurrency is the concurrency with the highest throughput, +Inf when the throughput
+	// grows without limit and 1 when it doesn't grow at all
+	PeakConcurrency float64
+	PeakThroughput  float64 // throughput at PeakConcurrency, the asymptote λ/σ for growing Amdahl
+}
+
+// Predict returns the throughput of the model at the given concurrency
+func (f *Fit) Predict(n float64) float64 {
+	return f.Lambda * capacity(n, f.Sigma, f.Kappa)
+}
+
+// Analysis holds both models fitted to the series of a metric
+type Analysis struct {
+	Metric    string
+	Parameter string  // value parameter holding the concurrency
+	Points    []Point // ordered by concurrency
+	Amdahl    Fit
+	USL       Fit
+}
+
+// Prediction compares throughputs of the models at a level of concurrency
+type Prediction struct {
+	N         float64
+	Baseline  float64
+	Candidate float64
+	Change    float64 // relative change
+}
+
+// Comparison compares USL fits of two executions
+type Comparison struct {
+	Baseline    *Analysis
+	Candidate   *Analysis
+	LambdaDelta float64 // relative change of Lambda
+	SigmaDelta  float64 // absolute change of Sigma
+	KappaDelta  float64 // absolute change of Kappa
+	PeakDelta   float64 // relative change of the peak throughput
+	// Predictions at the concurrency levels of both executions, ordered by concurrency
+	Predictions []Prediction
+	// Verdict on the peak throughput, which is higher better
+	Verdict compare.Verdict
+}
+
+// Analyze fits both models to values of the metric of the execution keyed by the value parameter
+// holding the concurrency, e.g. "client". Values with non-numeric or non-positive concurrency are
+// ignored. Needs at least three concurrency levels.
+func Analyze(exec *apis.TestExecution, metric, parameter string) (*Analysis, error) {
+	series := exec.Series(metric, parameter)
+	a := &Analysis{Metric: metric, Parameter: parameter}
+	for _, p := range series.Points {
+		n, err := strconv.ParseFloat(p.Key, 64)
+		if err != nil || n <= 0 {
+			continue
+		}
+		a.Points = append(a.Points, Point{N: n, Throughput: p.Result})
+	}
+	if len(a.Points) < minPoints {
+		return nil, errors.Errorf("Metric %s needs at least %d levels of %s, got %d",
+			metric, minPoints, parameter, len(a.Points))
+	}
+	a.Amdahl = FitModel(Amdahl, a.Points)
+	a.USL = FitModel(USL, a.Points)
+	return a, nil
+}
+
+// Compare fits both models to the metric of the baseline and candidate executions and compares
+// their USL fits
+func Compare(baseline, candidate *apis.TestExecution, metric, parameter string) (*Comparison, error) {
+	b, err := Analyze(baseline, metric, parameter)
+	if err != nil {
+		return nil, errors.Wrap(err, "Failed to analyze baseline scalability")
+	}
+	c, err := Analyze(candidate, metric, parameter)
+	if err != nil {
+		return nil, errors.Wrap(err, "Failed to analyze candidate scalability")
+	}
+	cmp := &Comparison{
+		Baseline:    b,
+		Candidate:   c,
+		LambdaDelta: compare.RelativeChange(b.USL.Lambda, c.USL.Lambda),
+		SigmaDelta:  c.USL.Sigma - b.USL.Sigma,
+		KappaDelta:  c.USL.Kappa - b.USL.Kappa,
+		PeakDelta:   compare.RelativeChange(b.USL.PeakThroughput, c.USL.PeakThroughput),
+	}
+	levels := make(map[float64]bool)
+	for _, p := range append(append([]Point(nil), b.Points...), c.Points...) {
+		if levels[p.N] {
+			continue
+		}
+		levels[p.N] = true
+		pred := Prediction{N: p.N, Baseline: b.USL.Predict(p.N), Candidate: c.USL.Predict(p.N)}
+		pred.Change = compare.RelativeChange(pred.Baseline, pred.Candidate)
+		cmp.Predictions = append(cmp.Predictions, pred)
+	}
+	sort.Slice(cmp.Predictions, func(i, j int) bool {
+		return cmp.Predictions[i].N < cmp.Predictions[j].N
+	})
+	cmp.Verdict = compare.Judge(apis.HBComparator, c.USL.PeakThroughput-b.USL.PeakThroughput,
+		cmp.PeakDelta, compare.Threshold{})
+	return cmp, nil
+}
+
+// FitModel fits the model to the points by least squares. For given σ and κ the optimal λ has
+// a closed form, σ and κ are searched by the Nelder-Mead method constrained to non-negative values.
+func FitModel(model Model, points []Point) Fit {
+	fit := Fit{Model: model}
+	lambda := func(sigma, kappa float64) float64 {
+		num, den := 0.0, 0.0
+		for _, p := range points {
+			c := capacity(p.N, sigma, kappa)
+			num += p.Throughput * c
+			den += c * c
+		}
+		return num / den
+	}
+	sse := func(sigma, kappa float64) float64 {
+		l := lambda(sigma, kappa)
+		sum := 0.0
+		for _, p := range points {
+			r := p.Throughput - l*capacity(p.N, sigma, kappa)
+			sum += r * r
+		}
+		return sum
+	}
+
+	if model == Amdahl {
+		best := minimize(func(x []float64) float64 {
+			return sse(math.Abs(x[0]), 0)
+		}, []float64{0.05}, []float64{0.1})
+		fit.Sigma = math.Abs(best[0])
+	} else {
+		best := minimize(func(x []float64) float64 {
+			return sse(math.Abs(x[0]), math.Abs(x[1]))
+		}, []float64{0.05, 0.001}, []float64{0.1, 0.01})
+		fit.Sigma, fit.Kappa = math.Abs(best[0]), math.Abs(best[1])
+	}
+	fit.Lambda = lambda(fit.Sigma, fit.Kappa)
+
+	throughputs := make([]float64, len(points))
+	for i, p := range points {
+		throughputs[i] = p.Throughput
+	}
+	mean := stats.Mean(throughputs)
+	total := 0.0
+	for _, t := range throughputs {
+		total += (t - mean) * (t - mean)
+	}
+	if total > 0 {
+		fit.RSquared = 1 - sse(fit.Sigma, fit.Kappa)/total
+	}
+
+	switch {
+	case fit.Kappa > 0 && fit.Sigma < 1:
+		fit.PeakConcurrency = math.Sqrt((1 - fit.Sigma) / fit.Kappa)
+		fit.PeakThroughput = fit.Predict(fit.PeakConcurrency)
+	case fit.Kappa > 0 || fit.Sigma >= 1:
+		//contention alone stops any scaling, the peak is the single unit
+		fit.PeakConcurrency = 1
+		fit.PeakThroughput = fit.Lambda
+	case fit.Sigma > 0:
+		fit.PeakConcurrency = math.Inf(1)
+		fit.PeakThroughput = fit.Lambda / fit.Sigma
+	default:
+		fit.PeakConcurrency = math.Inf(1)
+		fit.PeakThroughput = math.Inf(1)
+	}
+	return fit
+}
+
+// capacity returns the relative capacity C(N) = N / (1 + σ(N-1) + κN(N-1))
+func capacity(n, sigma, kappa float64) float64 {
+	return n / (1 + sigma*(n-1) + kappa*n*(n-1))
+}
+
+// minimize finds a local minimum of f by the Nelder-Mead method starting from the simplex
+// around start with the given steps
+func minimize(f func([]float64) float64, start, steps []float64) []float64 {
+	const (
+		maxIterations = 2000
+		tolerance     = 1e-12
+	)
+	dim := len(start)
+	simplex := make([][]float64, dim+1)
+	values := make([]float64, dim+1)
+	for i := range simplex {
+		simplex[i] = append([]float64(nil), start...)
+		if i > 0 {
+			simplex[i][i-1] += steps[i-1]
+		}
+		values[i] = f(simplex[i])
+	}
+	//point returns centroid + coef*(centroid - worst)
+	point := func(centroid, worst []float64, coef float64) []float64 {
+		p := make([]float64, dim)
+		for i := range p {
+			p[i] = centroid[i] + coef*(centroid[i]-worst[i])
+		}
+		return p
+	}
+
+	for it := 0; it < maxIterations; it++ {
+		order := make([]int, dim+1)
+		for i := range order {
+			order[i] = i
+		}
+		sort.Slice(order, func(i, j int) bool {
+			return values[order[i]] < values[order[j]]
+		})
+		best, worst, second := order[0], order[dim], order[dim-1]
+		if math.Abs(values[worst]-values[best]) <= tolerance*(math.Abs(values[best])+tolerance) {
+			break
+		}
+		centroid := make([]float64, dim)
+		for _, i := range order[:dim] {
+			for j := range centroid {
+				centroid[j] += simplex[i][j] / float64(dim)
+			}
+		}
+
+		reflected := point(centroid, simplex[worst], 1)
+		fr := f(reflected)
+		switch {
+		case fr < values[best]:
+			expanded := point(centroid, simplex[worst], 2)
+			if fe := f(expanded); fe < fr {
+				simplex[worst], values[worst] = expanded, fe
+			} else {
+				simplex[worst], values[worst] = reflected, fr
+			}
+		case fr < values[second]:
+			simplex[worst], values[worst] = reflected, fr
+		default:
+			contracted := point(centroid, simplex[worst], -0.5)
+			if fc := f(contracted); fc < values[worst] {
+				simplex[worst], values[worst] = contracted, fc
+				continue
+			}
+			//shrink towards the best point
+			for _, i := range order[1:] {
+				for j := range simplex[i] {
+					simplex[i][j] = simplex[best][j] + 0.5*(simplex[i][j]-simplex[best][j])
+				}
+				values[i] = f(simplex[i])
+			}
+		}
+	}
+	best := 0
+	for i := range values {
+		if values[i] < values[best] {
+			best = i
+		}
+	}
+	return simplex[best]
+}
@@ -0,0 +1,75 @@
+package scalability
+
+import (
+	"math"
+	"strconv"
+	"testing"
+
+	"github.com/mgencur/go-perfrepoclient/pkg/apis"
+	"github.com/mgencur/go-perfrepoclient/pkg/compare"
+)
+
+// execution returns an execution with throughput following USL for clients 1..64
+func execution(lambda, sigma, kappa float64) *apis.TestExecution {
+	exec := &apis.TestExecution{}
+	for _, n := range []float64{1, 2, 4, 8, 16, 32, 64} {
+		exec.Values = append(exec.Values, apis.Value{
+			MetricName: "throughput",
+			Result:     lambda * capacity(n, sigma, kappa),
+			Parameters: []apis.ValueParameter{{Name: "client", Value: strconv.Itoa(int(n))}},
+		})
+	}
+	return exec
+}
+
+func TestAnalyze(t *testing.T) {
+	a, err := Analyze(execution(100, 0.05, 0.0005), "throughput", "client")
+	if err != nil {
+		t.Fatal(err)
+	}
+	usl := a.USL
+	if math.Abs(usl.Lambda-100) > 0.1 || math.Abs(usl.Sigma-0.05) > 1e-3 ||
+		math.Abs(usl.Kappa-0.0005) > 1e-5 || usl.RSquared < 0.9999 {
+		t.Errorf("Unexpected USL fit %+v", usl)
+	}
+	if math.Abs(usl.PeakConcurrency-math.Sqrt(0.95/0.0005)) > 1 {
+		t.Errorf("Unexpected peak concurrency %v", usl.PeakConcurrency)
+	}
+	if a.Amdahl.RSquared >= usl.RSquared || !math.IsInf(a.Amdahl.PeakConcurrency, 1) {
+		t.Errorf("Unexpected Amdahl fit %+v", a.Amdahl)
+	}
+
+	if _, err := Analyze(&apis.TestExecution{}, "throughput", "client"); err == nil {
+		t.Error("Expected an error for a metric without enough levels")
+	}
+}
+
+func TestFitNoScaling(t *testing.T) {
+	//with σ >= 1 the throughput decreases from a single unit of concurrency
+	for _, model := range []Model{Amdahl, USL} {
+		var points []Point
+		for _, n := range []float64{1, 2, 4, 8, 16} {
+			points = append(points, Point{N: n, Throughput: 100 * capacity(n, 1.5, 0)})
+		}
+		fit := FitModel(model, points)
+		if math.Abs(fit.Sigma-1.5) > 1e-2 || fit.Kappa > 1e-4 {
+			t.Errorf("%s: unexpected coefficients %+v", model.String(), fit)
+		}
+		if fit.PeakConcurrency != 1 || math.Abs(fit.PeakThroughput-100) > 0.1 {
+			t.Errorf("%s: expected peak at a single unit, got %+v", model.String(), fit)
+		}
+	}
+}
+
+func TestCompare(t *testing.T) {
+	cmp, err := Compare(execution(100, 0.05, 0.0005), execution(100, 0.05, 0.002), "throughput", "client")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cmp.Verdict != compare.Regressed || cmp.KappaDelta <= 0 || len(cmp.Predictions) != 7 {
+		t.Errorf("Unexpected comparison %+v", cmp)
+	}
+	if p := cmp.Predictions[6]; p.N != 64 || p.Change >= 0 {
+		t.Errorf("Unexpected prediction %+v", p)
+	}
+}
@@ -0,0 +1,126 @@
+// Package score combines metrics of a test into a single composite performance score.
+package score
+
+import (
+	"math"
+
+	"github.com/pkg/errors"
+
+	"github.com/mgencur/go-perfrepoclient/pkg/apis"
+)
+
+// Options configures the score
+type Options struct {
+	Weights map[string]float64 // weights by metric name, metrics without a weight have weight 1
+	// Comparators by metric name, take precedence over comparators of the test metrics
+	Comparators map[string]apis.Comparator
+}
+
+// Contribution is the part of the score contributed by a metric
+type Contribution struct {
+	Metric     string
+	Comparator apis.Comparator
+	Weight     float64 // weight normalized so that the weights of all scored metrics sum to 1
+	Values     int     // number of values, more than one for multi-value metrics
+	// Ratio is the candidate relative to the baseline, greater than 1 when the candidate is better.
+	// Values of multi-value metrics are combined by geometric mean.
+	Ratio float64
+	// Factor is Ratio raised to Weight, the score is the product of factors of all metrics
+	Factor float64
+}
+
+// Skipped is a metric excluded from the score
+type Skipped struct {
+	Metric string
+	Reason string
+}
+
+// Score is the weighted geometric mean of ratios of the metrics. A score of 1.1 means that the
+// candidate is 10% better than the baseline.
+type Score struct {
+	Value         float64
+	Contributions []Contribution // in order of the test metrics
+	Skipped       []Skipped
+}
+
+// Compute scores the candidate execution against the baseline execution. Each metric of the test is
+// normalized by its baseline value and inverted when lower is better. Metrics with unknown comparator,
+// zero weight, missing or non-positive values are skipped. Returns an error when no metric can be scored.
+func Compute(test *apis.Test, baseline, candidate *apis.TestExecution, opts Options) (*Score, error) {
+	s := &Score{}
+	totalWeight := 0.0
+	for _, m := range test.Metrics {
+		comparator := m.Comparator
+		if c, ok := opts.Comparators[m.Name]; ok {
+			comparator = c
+		}
+		weight := 1.0
+		if w, ok := opts.Weights[m.Name]; ok {
+			weight = w
+		}
+		switch {
+		case comparator == apis.UnknownComparator:
+			s.Skipped = append(s.Skipped, Skipped{Metric: m.Name, Reason: "unknown comparator"})
+			continue
+		case weight <= 0:
+			s.Skipped = append(s.Skipped, Skipped{Metric: m.Name, Reason: "zero weight"})
+			continue
+		}
+
+		logRatio, count, reason := metricLogRatio(m.Name, comparator, baseline, candidate)
+		if reason != "" {
+			s.Skipped = append(s.Skipped, Skipped{Metric: m.Name, Reason: reason})
+			continue
+		}
+		s.Contributions = append(s.Contributions, Contribution{
+			Metric:     m.Name,
+			Comparator: comparator,
+			Weight:     weight,
+			Values:     count,
+			Ratio:      math.Exp(logRatio),
+		})
+		totalWeight += weight
+	}
+	if len(s.Contributions) == 0 {
+		return nil, errors.Errorf("No metric of test %s can be scored", test.Name)
+	}
+
+	logScore := 0.0
+	for i := range s.Contributions {
+		c := &s.Contributions[i]
+		c.Weight /= totalWeight
+		c.Factor = math.Pow(c.Ratio, c.Weight)
+		logScore += c.Weight * math.Log(c.Ratio)
+	}
+	s.Value = math.Exp(logScore)
+	return s, nil
+}
+
+// metricLogRatio returns the mean log ratio of values of the metric matched by their value parameters
+// and the number of matched values, or the reason why the metric can't be scored
+func metricLogRatio(metric string, comparator apis.Comparator, baseline, candidate *apis.TestExecution) (float64, int, string) {
+	base := make(map[string]float64)
+	for _, v := range baseline.MetricValues(metric) {
+		base[v.ParametersKey()] = v.Result
+	}
+	sum, count := 0.0, 0
+	for _, v := range candidate.MetricValues(metric) {
+		b, ok := base[v.ParametersKey()]
+		if !ok {
+			continue
+		}
+		if b <= 0 || v.Result <= 0 {
+			return 0, 0, "non-positive value"
+		}
+		ratio := v.Result / b
+		if comparator == apis.LBComparator {
+			ratio = 1 / ratio
+		}
+		sum += math.Log(ratio)
+		count++
+	}
+	if count == 0 {
+		return 0, 0, "no matching values"
+	}
+	return sum / float64(count), count, ""
+}
@@ -0,0 +1,54 @@
+package score
+
+import (
+	"math"
+	"testing"
+
+	"github.com/mgencur/go-perfrepoclient/pkg/apis"
+)
+
+func TestCompute(t *testing.T) {
+	test := &apis.Test{
+		Name: "service",
+		Metrics: []apis.Metric{
+			{Name: "latency", Comparator: apis.LBComparator},
+			{Name: "throughput", Comparator: apis.HBComparator},
+			{Name: "memory", Comparator: apis.LBComparator},
+			{Name: "unknown"},
+		},
+	}
+	baseline := &apis.TestExecution{Values: []apis.Value{
+		{MetricName: "latency", Result: 100},
+		{MetricName: "throughput", Result: 1000},
+		{MetricName: "unknown", Result: 1},
+	}}
+	candidate := &apis.TestExecution{Values: []apis.Value{
+		{MetricName: "latency", Result: 50},
+		{MetricName: "throughput", Result: 500},
+		{MetricName: "unknown", Result: 2},
+	}}
+
+	s, err := Compute(test, baseline, candidate, Options{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	//twice as fast and half the throughput cancel out
+	if math.Abs(s.Value-1) > 1e-12 || len(s.Contributions) != 2 || len(s.Skipped) != 2 {
+		t.Fatalf("Unexpected score %+v", s)
+	}
+	if c := s.Contributions[0]; c.Ratio != 2 || c.Weight != 0.5 || math.Abs(c.Factor-math.Sqrt2) > 1e-12 {
+		t.Errorf("Unexpected contribution %+v", c)
+	}
+
+	s, err = Compute(test, baseline, candidate, Options{Weights: map[string]float64{"latency": 3}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if math.Abs(s.Value-math.Pow(2, 0.5)) > 1e-12 {
+		t.Errorf("Unexpected weighted score %v", s.Value)
+	}
+
+	if _, err := Compute(test, baseline, candidate, Options{Weights: map[string]float64{"latency": 0, "throughput": 0}}); err == nil {
+		t.Error("Expected an error when no metric is scored")
+	}
+}
@@ -0,0 +1,105 @@
+package stats
+
+import "math"
+
+// ANOVAResult holds the outcome of a one-way analysis of variance
+type ANOVAResult struct {
+	F         float64
+	PValue    float64
+	DFBetween float64
+	DFWithin  float64
+	// EtaSquared is the fraction of the variance explained by the groups
+	EtaSquared float64
+	// OmegaSquared is the less biased estimate of the explained fraction, it penalizes many small groups
+	OmegaSquared float64
+}
+
+// OneWayANOVA tests whether means of the groups differ. Empty groups are ignored. Needs at least two
+// groups and more values than groups, otherwise the p-value is NaN.
+func OneWayANOVA(groups [][]float64) ANOVAResult {
+	var all []float64
+	k := 0
+	for _, g := range groups {
+		if len(g) > 0 {
+			all = append(all, g...)
+			k++
+		}
+	}
+	n := len(all)
+	nan := math.NaN()
+	if k < 2 || n <= k {
+		return ANOVAResult{F: nan, PValue: nan, EtaSquared: nan, OmegaSquared: nan}
+	}
+	mean := Mean(all)
+	between, within := 0.0, 0.0
+	for _, g := range groups {
+		if len(g) == 0 {
+			continue
+		}
+		gm := Mean(g)
+		between += float64(len(g)) * (gm - mean) * (gm - mean)
+		for _, v := range g {
+			within += (v - gm) * (v - gm)
+		}
+	}
+	r := ANOVAResult{DFBetween: float64(k - 1), DFWithin: float64(n - k)}
+	total := between + within
+	if total == 0 {
+		r.F, r.PValue = 0, 1
+		return r
+	}
+	r.EtaSquared = between / total
+	msWithin := within / r.DFWithin
+	r.OmegaSquared = math.Max(0, (between-r.DFBetween*msWithin)/(total+msWithin))
+	if within == 0 {
+		r.F, r.PValue = math.Inf(1), 0
+		return r
+	}
+	r.F = (between / r.DFBetween) / msWithin
+	r.PValue = 1 - FCDF(r.F, r.DFBetween, r.DFWithin)
+	return r
+}
+
+// FCDF returns the cumulative distribution function of the F distribution with d1 and d2 degrees of freedom
+func FCDF(x, d1, d2 float64) float64 {
+	if x <= 0 {
+		return 0
+	}
+	if math.IsInf(x, 1) {
+		return 1
+	}
+	return RegularizedIncompleteBeta(d1*x/(d1*x+d2), d1/2, d2/2)
+}
+
+// Pearson returns the Pearson correlation coefficient of x and y with the two-sided test of zero
+// correlation. The statistic of the test is the t statistic with n-2 degrees of freedom.
+func Pearson(x, y []float64) (float64, TestResult) {
+	n := len(x)
+	nan := math.NaN()
+	if n < 3 || len(y) != n {
+		return nan, TestResult{Statistic: nan, PValue: nan, DF: nan}
+	}
+	mx, my := Mean(x), Mean(y)
+	sxy, sxx, syy := 0.0, 0.0, 0.0
+	for i := range x {
+		sxy += (x[i] - mx) * (y[i] - my)
+		sxx += (x[i] - mx) * (x[i] - mx)
+		syy += (y[i] - my) * (y[i] - my)
+	}
+	df := float64(n - 2)
+	if sxx == 0 || syy == 0 {
+		return 0, TestResult{Statistic: 0, PValue: 1, DF: df}
+	}
+	r := sxy / math.Sqrt(sxx*syy)
+	if math.Abs(r) >= 1 {
+		return r, TestResult{Statistic: math.Copysign(math.Inf(1), r), PValue: 0, DF: df}
+	}
+	t := r * math.Sqrt(df/(1-r*r))
+	return r, TestResult{Statistic: t, PValue: 2 * StudentTCDF(-math.Abs(t), df), DF: df}
+}
+
+// Spearman returns the Spearman rank correlation coefficient of x and y with the test of zero
+// correlation using the t approximation
+func Spearman(x, y []float64) (float64, TestResult) {
+	return Pearson(Ranks(x), Ranks(y))
+}
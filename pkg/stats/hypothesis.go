@@ -0,0 +1,182 @@
+package stats
+
+import (
+	"math"
+	"math/rand"
+)
+
+// maxExactMannWhitney is the largest sample size for which the exact distribution of
+// the Mann-Whitney U statistic is used
+const maxExactMannWhitney = 20
+
+// TestResult holds the outcome of a two-sided hypothesis test
+type TestResult struct {
+	Statistic float64
+	PValue    float64
+	DF        float64 // degrees of freedom, only set by tests based on the t distribution
+}
+
+// Interval is a confidence interval
+type Interval struct {
+	Lower float64
+	Upper float64
+}
+
+// Contains returns true when the value lies within the interval
+func (i Interval) Contains(v float64) bool {
+	return i.Lower <= v && v <= i.Upper
+}
+
+// WelchTTest tests whether the means of y and x differ without assuming equal variances.
+// The statistic is positive when the mean of y is greater. Both samples need at least two values,
+// otherwise the p-value is NaN.
+func WelchTTest(x, y []float64) TestResult {
+	nx, ny := float64(len(x)), float64(len(y))
+	if nx < 2 || ny < 2 {
+		return TestResult{Statistic: math.NaN(), PValue: math.NaN(), DF: math.NaN()}
+	}
+	vx, vy := Variance(x)/nx, Variance(y)/ny
+	diff := Mean(y) - Mean(x)
+	if vx+vy == 0 {
+		if diff == 0 {
+			return TestResult{Statistic: 0, PValue: 1, DF: nx + ny - 2}
+		}
+		return TestResult{Statistic: math.Copysign(math.Inf(1), diff), PValue: 0, DF: nx + ny - 2}
+	}
+	t := diff / math.Sqrt(vx+vy)
+	df := (vx + vy) * (vx + vy) / (vx*vx/(nx-1) + vy*vy/(ny-1))
+	return TestResult{Statistic: t, PValue: 2 * StudentTCDF(-math.Abs(t), df), DF: df}
+}
+
+// MannWhitneyU tests whether values of y tend to be greater or lower than values of x.
+// The statistic is the U of y: the number of pairs in which the value of y is greater, ties
+// counting one half. The exact distribution is used for small samples without ties,
+// the normal approximation with tie and continuity correction otherwise.
+func MannWhitneyU(x, y []float64) TestResult {
+	nx, ny := len(x), len(y)
+	if nx == 0 || ny == 0 {
+		return TestResult{Statistic: math.NaN(), PValue: math.NaN()}
+	}
+	all := append(append([]float64(nil), x...), y...)
+	ranks := Ranks(all)
+	rankSumY := 0.0
+	for _, r := range ranks[nx:] {
+		rankSumY += r
+	}
+	u := rankSumY - float64(ny*(ny+1))/2
+
+	ties := tieCorrection(all)
+	if ties == 0 && nx <= maxExactMannWhitney && ny <= maxExactMannWhitney {
+		return TestResult{Statistic: u, PValue: exactMannWhitneyP(u, nx, ny)}
+	}
+
+	n := float64(nx + ny)
+	mean := float64(nx*ny) / 2
+	sigma := math.Sqrt(float64(nx*ny) / 12 * ((n + 1) - ties/(n*(n-1))))
+	if sigma == 0 {
+		return TestResult{Statistic: u, PValue: 1}
+	}
+	diff := math.Abs(u-mean) - 0.5
+	if diff < 0 {
+		diff = 0
+	}
+	return TestResult{Statistic: u, PValue: math.Min(1, 2*NormalCDF(-diff/sigma))}
+}
+
+// tieCorrection returns sum of t^3 - t over groups of t tied values
+func tieCorrection(values []float64) float64 {
+	sorted := Sorted(values)
+	sum := 0.0
+	for i := 0; i < len(sorted); {
+		j := i
+		for j+1 < len(sorted) && sorted[j+1] == sorted[i] {
+			j++
+		}
+		t := float64(j - i + 1)
+		sum += t*t*t - t
+		i = j + 1
+	}
+	return sum
+}
+
+// exactMannWhitneyP returns the two-sided p-value of the U statistic using its exact
+// distribution under the null hypothesis
+func exactMannWhitneyP(u float64, nx, ny int) float64 {
+	//counts[m][n][k] is the number of arrangements of m values of x and n values of y with U == k.
+	//The greatest value either belongs to y and beats all m values of x or belongs to x.
+	counts := make([][][]float64, nx+1)
+	for m := 0; m <= nx; m++ {
+		counts[m] = make([][]float64, ny+1)
+		for n := 0; n <= ny; n++ {
+			counts[m][n] = make([]float64, m*n+1)
+			if m == 0 || n == 0 {
+				counts[m][n][0] = 1
+				continue
+			}
+			for k := 0; k <= m*n; k++ {
+				if k-m >= 0 {
+					counts[m][n][k] += counts[m][n-1][k-m]
+				}
+				if k <= (m-1)*n {
+					counts[m][n][k] += counts[m-1][n][k]
+				}
+			}
+		}
+	}
+	dist := counts[nx][ny]
+	total, lower, upper := 0.0, 0.0, 0.0
+	for k, c := range dist {
+		total += c
+		if float64(k) <= u {
+			lower += c
+		}
+		if float64(k) >= u {
+			upper += c
+		}
+	}
+	return math.Min(1, 2*math.Min(lower, upper)/total)
+}
+
+// CohensD returns the difference of means of y and x in units of their pooled standard deviation
+func CohensD(x, y []float64) float64 {
+	nx, ny := float64(len(x)), float64(len(y))
+	pooled := math.Sqrt(((nx-1)*Variance(x) + (ny-1)*Variance(y)) / (nx + ny - 2))
+	diff := Mean(y) - Mean(x)
+	if pooled == 0 {
+		if diff == 0 {
+			return 0
+		}
+		return math.Copysign(math.Inf(1), diff)
+	}
+	return diff / pooled
+}
+
+// RankBiserial returns the rank-biserial correlation between x and y, which ranges from -1
+// (all values of y are lower) to 1 (all values of y are greater)
+func RankBiserial(x, y []float64) float64 {
+	u := MannWhitneyU(x, y).Statistic
+	return 2*u/float64(len(x)*len(y)) - 1
+}
+
+// BootstrapMedianDifference returns the percentile bootstrap confidence interval of the difference
+// between medians of y and x. Uses the given number of resamples drawn with rng.
+func BootstrapMedianDifference(x, y []float64, resamples int, confidence float64, rng *rand.Rand) Interval {
+	if len(x) == 0 || len(y) == 0 || resamples <= 0 {
+		return Interval{Lower: math.NaN(), Upper: math.NaN()}
+	}
+	diffs := make([]float64, resamples)
+	sx := make([]float64, len(x))
+	sy := make([]float64, len(y))
+	for i := range diffs {
+		for j := range sx {
+			sx[j] = x[rng.Intn(len(x))]
+		}
+		for j := range sy {
+			sy[j] = y[rng.Intn(len(y))]
+		}
+		diffs[i] = Median(sy) - Median(sx)
+	}
+	alpha := (1 - confidence) / 2
+	sorted := Sorted(diffs)
+	return Interval{Lower: quantileSorted(sorted, alpha), Upper: quantileSorted(sorted, 1-alpha)}
+}
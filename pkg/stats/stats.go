@@ -0,0 +1,99 @@
+// Package stats provides statistical functions used for analysis of metric values of test executions.
+package stats
+
+import (
+	"math"
+	"sort"
+)
+
+// Mean returns the arithmetic mean of the values or NaN when there are none
+func Mean(values []float64) float64 {
+	if len(values) == 0 {
+		return math.NaN()
+	}
+	sum := 0.0
+	for _, v := range values {
+		sum += v
+	}
+	return sum / float64(len(values))
+}
+
+// Variance returns the sample variance of the values or NaN when there are fewer than two
+func Variance(values []float64) float64 {
+	if len(values) < 2 {
+		return math.NaN()
+	}
+	mean := Mean(values)
+	sum := 0.0
+	for _, v := range values {
+		sum += (v - mean) * (v - mean)
+	}
+	return sum / float64(len(values)-1)
+}
+
+// StdDev returns the sample standard deviation of the values
+func StdDev(values []float64) float64 {
+	return math.Sqrt(Variance(values))
+}
+
+// Median returns the median of the values or NaN when there are none
+func Median(values []float64) float64 {
+	return Quantile(values, 0.5)
+}
+
+// Quantile returns the q-th quantile (0 <= q <= 1) of the values using linear interpolation
+// between closest ranks. Returns NaN when there are no values.
+func Quantile(values []float64, q float64) float64 {
+	if len(values) == 0 || q < 0 || q > 1 {
+		return math.NaN()
+	}
+	return quantileSorted(Sorted(values), q)
+}
+
+func quantileSorted(sorted []float64, q float64) float64 {
+	pos := q * float64(len(sorted)-1)
+	lower := int(math.Floor(pos))
+	upper := int(math.Ceil(pos))
+	return sorted[lower] + (sorted[upper]-sorted[lower])*(pos-float64(lower))
+}
+
+// Sorted returns a sorted copy of the values
+func Sorted(values []float64) []float64 {
+	sorted := append([]float64(nil), values...)
+	sort.Float64s(sorted)
+	return sorted
+}
+
+// MedianAbsoluteDeviation returns the median of absolute deviations from the median
+func MedianAbsoluteDeviation(values []float64) float64 {
+	median := Median(values)
+	deviations := make([]float64, len(values))
+	for i, v := range values {
+		deviations[i] = math.Abs(v - median)
+	}
+	return Median(deviations)
+}
+
+// Ranks returns ranks (starting at 1) of the values, tied values get the average of their ranks
+func Ranks(values []float64) []float64 {
+	indexes := make([]int, len(values))
+	for i := range indexes {
+		indexes[i] = i
+	}
+	sort.SliceStable(indexes, func(i, j int) bool {
+		return values[indexes[i]] < values[indexes[j]]
+	})
+	ranks := make([]float64, len(values))
+	for i := 0; i < len(indexes); {
+		j := i
+		for j+1 < len(indexes) && values[indexes[j+1]] == values[indexes[i]] {
+			j++
+		}
+		rank := float64(i+j)/2 + 1
+		for k := i; k <= j; k++ {
+			ranks[indexes[k]] = rank
+		}
+		i = j + 1
+	}
+	return ranks
+}
@@ -0,0 +1,98 @@
+package stats
+
+import (
+	"math"
+	"math/rand"
+	"testing"
+)
+
+func near(a, b, tolerance float64) bool {
+	return math.Abs(a-b) <= tolerance
+}
+
+func TestDescriptive(t *testing.T) {
+	values := []float64{4, 1, 3, 2, 10}
+	if Mean(values) != 4 || Median(values) != 3 || Quantile(values, 0.25) != 2 ||
+		!near(Variance(values), 12.5, 1e-12) || MedianAbsoluteDeviation(values) != 1 {
+		t.Errorf("Unexpected descriptive statistics of %v", values)
+	}
+	ranks := Ranks([]float64{10, 20, 10, 30})
+	if ranks[0] != 1.5 || ranks[1] != 3 || ranks[2] != 1.5 || ranks[3] != 4 {
+		t.Errorf("Unexpected ranks %v", ranks)
+	}
+}
+
+func TestDistributions(t *testing.T) {
+	if !near(NormalCDF(1.96), 0.975, 1e-4) || !near(NormalQuantile(0.975), 1.959964, 1e-5) {
+		t.Error("Unexpected normal distribution values")
+	}
+	//reference values from tables of the t distribution
+	if !near(StudentTCDF(2.228, 10), 0.975, 1e-4) || !near(StudentTQuantile(0.975, 5), 2.5706, 1e-4) {
+		t.Error("Unexpected t distribution values")
+	}
+}
+
+func TestWelchTTest(t *testing.T) {
+	x := []float64{19.8, 20.4, 19.6, 17.8, 18.5, 18.9, 18.3, 18.9, 19.5, 22.0}
+	y := []float64{28.2, 26.6, 20.1, 23.3, 25.2, 22.1, 17.7, 27.6, 20.6, 13.7, 23.2, 17.5, 20.6, 18.0, 23.9, 21.6, 24.3, 20.4, 23.9, 13.3}
+	r := WelchTTest(x, y)
+	//reference p-value obtained by numerical integration of the t distribution density
+	if !near(r.Statistic, 2.2255, 1e-4) || !near(r.DF, 24.5246, 1e-4) || !near(r.PValue, 0.035485, 1e-6) {
+		t.Errorf("Unexpected Welch t-test result %+v", r)
+	}
+}
+
+func TestMannWhitneyU(t *testing.T) {
+	x := []float64{1, 2, 3, 4, 5}
+	y := []float64{6, 7, 8, 9, 10}
+	r := MannWhitneyU(x, y)
+	//exact p-value is 2/252
+	if r.Statistic != 25 || !near(r.PValue, 2.0/252, 1e-12) {
+		t.Errorf("Unexpected exact Mann-Whitney result %+v", r)
+	}
+	if RankBiserial(x, y) != 1 || RankBiserial(y, x) != -1 {
+		t.Error("Unexpected rank-biserial correlation")
+	}
+	r = MannWhitneyU([]float64{1, 2, 2, 3}, []float64{2, 3, 3, 4})
+	if r.Statistic != 13 || !near(r.PValue, 0.1720, 1e-4) {
+		t.Errorf("Unexpected Mann-Whitney result with ties %+v", r)
+	}
+}
+
+func TestBootstrapMedianDifference(t *testing.T) {
+	x := []float64{10, 11, 9, 10, 10.5, 9.5, 10.2}
+	y := []float64{12, 13, 11.5, 12.5, 12.2, 11.8, 12.1}
+	ci := BootstrapMedianDifference(x, y, 1000, 0.95, rand.New(rand.NewSource(1)))
+	if ci.Contains(0) || !ci.Contains(2) {
+		t.Errorf("Unexpected bootstrap interval %+v", ci)
+	}
+}
+
+func TestTheilSen(t *testing.T) {
+	x := []float64{0, 1, 2, 3, 4, 5, 6, 7, 8, 9}
+	y := []float64{10, 12, 14, 16, 18, 100, 22, 24, 26, 28}
+	line, interval := TheilSen(x, y, 0.95)
+	//the outlier doesn't affect the slope
+	if line.Slope != 2 || line.Intercept != 10 || !interval.Contains(2) || interval.Contains(0) {
+		t.Errorf("Unexpected fit %+v %+v", line, interval)
+	}
+	if r := MannKendall(x, y); r.Statistic <= 0 || r.PValue >= 0.01 {
+		t.Errorf("Trend not detected %+v", r)
+	}
+	if r := MannKendall(x, []float64{5, 3, 6, 4, 5, 3, 6, 4, 5, 4}); r.PValue < 0.5 {
+		t.Errorf("Unexpected trend %+v", r)
+	}
+}
+
+func TestANOVA(t *testing.T) {
+	//reference values computed by scipy.stats.f_oneway
+	r := OneWayANOVA([][]float64{{1, 2, 3}, {4, 5, 6}, {7, 8, 9}})
+	if r.F != 27 || !near(r.PValue, 0.001, 1e-6) || r.EtaSquared != 0.9 {
+		t.Errorf("Unexpected ANOVA result %+v", r)
+	}
+	rho, _ := Spearman([]float64{1, 2, 3, 4, 5}, []float64{1, 4, 9, 16, 25})
+	r2, test := Pearson([]float64{1, 2, 3, 4, 5}, []float64{2, 4, 5, 4, 5})
+	if rho != 1 || !near(r2, 0.7746, 1e-4) || !near(test.PValue, 0.1241, 1e-4) {
+		t.Errorf("Unexpected correlation %v %v %+v", rho, r2, test)
+	}
+}
@@ -0,0 +1,102 @@
+package stats
+
+import (
+	"math"
+	"sort"
+
+	"github.com/mgencur/go-perfrepoclient/pkg/apis"
+)
+
+// Description holds descriptive statistics of a set of values. StdDev and CV are zero when
+// they are undefined (a single value or zero mean) so that the description can be marshalled
+// to JSON.
+type Description struct {
+	Count  int     `json:"count"`
+	Mean   float64 `json:"mean"`
+	Median float64 `json:"median"`
+	StdDev float64 `json:"stddev"`
+	CV     float64 `json:"cv"` // coefficient of variation, StdDev / |Mean|
+	Min    float64 `json:"min"`
+	Max    float64 `json:"max"`
+	P5     float64 `json:"p5"`
+	P95    float64 `json:"p95"`
+}
+
+// Summary describes values of a metric sharing the same value parameters and, when grouped,
+// the same value of an execution parameter
+type Summary struct {
+	Metric      string `json:"metric"`
+	Parameters  string `json:"parameters,omitempty"` // value parameters, see apis.Value.ParametersKey
+	Group       string `json:"group,omitempty"`      // value of the execution parameter the summaries are grouped by
+	Description `json:"stats"`
+}
+
+// Describe computes descriptive statistics of the values. Returns a zero Description when there
+// are no values.
+func Describe(values []float64) Description {
+	if len(values) == 0 {
+		return Description{}
+	}
+	sorted := Sorted(values)
+	d := Description{
+		Count:  len(values),
+		Mean:   Mean(values),
+		Median: quantileSorted(sorted, 0.5),
+		Min:    sorted[0],
+		Max:    sorted[len(sorted)-1],
+		P5:     quantileSorted(sorted, 0.05),
+		P95:    quantileSorted(sorted, 0.95),
+	}
+	if len(values) > 1 {
+		d.StdDev = StdDev(values)
+	}
+	if d.Mean != 0 {
+		d.CV = d.StdDev / math.Abs(d.Mean)
+	}
+	return d
+}
+
+// Summarize describes values of each metric across the executions. Values of multi-value metrics
+// are summarized separately per value parameters. When groupBy is not empty the values are further
+// grouped by the value of that execution parameter; executions without it form the group "".
+// Summaries are ordered by metric, value parameters and group.
+func Summarize(executions []apis.TestExecution, groupBy string) []Summary {
+	type summaryKey struct {
+		metric string
+		params string
+		group  string
+	}
+	values := make(map[summaryKey][]float64)
+	for i := range executions {
+		group := ""
+		if groupBy != "" {
+			group = executions[i].ParametersMap()[groupBy]
+		}
+		for j := range executions[i].Values {
+			v := &executions[i].Values[j]
+			key := summaryKey{metric: v.MetricName, params: v.ParametersKey(), group: group}
+			values[key] = append(values[key], v.Result)
+		}
+	}
+
+	summaries := make([]Summary, 0, len(values))
+	for key, vs := range values {
+		summaries = append(summaries, Summary{
+			Metric:      key.metric,
+			Parameters:  key.params,
+			Group:       key.group,
+			Description: Describe(vs),
+		})
+	}
+	sort.Slice(summaries, func(i, j int) bool {
+		a, b := summaries[i], summaries[j]
+		if a.Metric != b.Metric {
+			return a.Metric < b.Metric
+		}
+		if a.Parameters != b.Parameters {
+			return a.Parameters < b.Parameters
+		}
+		return apis.LessParameterValue(a.Group, b.Group)
+	})
+	return summaries
+}
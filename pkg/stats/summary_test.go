@@ -0,0 +1,40 @@
+package stats
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/mgencur/go-perfrepoclient/pkg/apis"
+)
+
+func TestSummarize(t *testing.T) {
+	execution := func(jvm string, results ...float64) apis.TestExecution {
+		exec := apis.TestExecution{Parameters: []apis.TestExecutionParameter{{Name: "jvm", Value: jvm}}}
+		for _, r := range results {
+			exec.Values = append(exec.Values, apis.Value{MetricName: "latency", Result: r})
+		}
+		return exec
+	}
+	executions := []apis.TestExecution{
+		execution("11", 10, 14),
+		execution("8", 20),
+		execution("11", 12),
+	}
+
+	all := Summarize(executions, "")
+	if len(all) != 1 || all[0].Count != 4 || all[0].Mean != 14 || all[0].Min != 10 || all[0].Max != 20 {
+		t.Fatalf("Unexpected summary %+v", all)
+	}
+	grouped := Summarize(executions, "jvm")
+	if len(grouped) != 2 || grouped[0].Group != "8" || grouped[1].Group != "11" ||
+		grouped[1].Count != 3 || grouped[1].Median != 12 || !near(grouped[1].CV, 2.0/12, 1e-12) {
+		t.Fatalf("Unexpected grouped summaries %+v", grouped)
+	}
+	//a single value has no standard deviation but must still marshal
+	if grouped[0].StdDev != 0 {
+		t.Errorf("Unexpected standard deviation %+v", grouped[0])
+	}
+	if _, err := json.Marshal(grouped); err != nil {
+		t.Errorf("Failed to marshal summaries: %v", err)
+	}
+}
@@ -0,0 +1,100 @@
+package stats
+
+import (
+	"math"
+	"sort"
+)
+
+// Line is a fitted line y = Intercept + Slope*x
+type Line struct {
+	Slope     float64
+	Intercept float64
+}
+
+// TheilSen fits a line robust to outliers: the slope is the median of slopes between all pairs of
+// points with distinct x and the intercept is the median of y - slope*x. Also returns the confidence
+// interval of the slope based on the distribution of Kendall's S. Needs at least two distinct x.
+func TheilSen(x, y []float64, confidence float64) (Line, Interval) {
+	var slopes []float64
+	for i := range x {
+		for j := i + 1; j < len(x); j++ {
+			if x[i] != x[j] {
+				slopes = append(slopes, (y[j]-y[i])/(x[j]-x[i]))
+			}
+		}
+	}
+	if len(slopes) == 0 {
+		nan := math.NaN()
+		return Line{Slope: nan, Intercept: nan}, Interval{Lower: nan, Upper: nan}
+	}
+	sort.Float64s(slopes)
+	line := Line{Slope: quantileSorted(slopes, 0.5)}
+	residuals := make([]float64, len(x))
+	for i := range x {
+		residuals[i] = y[i] - line.Slope*x[i]
+	}
+	line.Intercept = Median(residuals)
+
+	//Sen's interval: the slopes of ranks (N-C)/2 and (N+C)/2+1 counting from one
+	c := NormalQuantile(1-(1-confidence)/2) * math.Sqrt(kendallVariance(y))
+	n := float64(len(slopes))
+	lower := int(math.Round((n-c)/2)) - 1
+	upper := int(math.Round((n + c) / 2))
+	if lower < 0 {
+		lower = 0
+	}
+	if upper > len(slopes)-1 {
+		upper = len(slopes) - 1
+	}
+	return line, Interval{Lower: slopes[lower], Upper: slopes[upper]}
+}
+
+// MannKendall tests whether y tends to increase or decrease with x. The statistic is Kendall's S,
+// positive for an increasing trend. Uses the normal approximation with tie and continuity correction.
+func MannKendall(x, y []float64) TestResult {
+	if len(x) < 3 {
+		return TestResult{Statistic: math.NaN(), PValue: math.NaN()}
+	}
+	s := 0.0
+	for i := range x {
+		for j := i + 1; j < len(x); j++ {
+			s += sign(x[j]-x[i]) * sign(y[j]-y[i])
+		}
+	}
+	variance := kendallVariance(y)
+	if variance == 0 {
+		return TestResult{Statistic: s, PValue: 1}
+	}
+	diff := math.Abs(s) - 1
+	if diff < 0 {
+		diff = 0
+	}
+	return TestResult{Statistic: s, PValue: math.Min(1, 2*NormalCDF(-diff/math.Sqrt(variance)))}
+}
+
+// kendallVariance returns the variance of Kendall's S under no trend, corrected for ties in y
+func kendallVariance(y []float64) float64 {
+	n := float64(len(y))
+	ties := 0.0
+	sorted := Sorted(y)
+	for i := 0; i < len(sorted); {
+		j := i
+		for j+1 < len(sorted) && sorted[j+1] == sorted[i] {
+			j++
+		}
+		t := float64(j - i + 1)
+		ties += t * (t - 1) * (2*t + 5)
+		i = j + 1
+	}
+	return (n*(n-1)*(2*n+5) - ties) / 18
+}
+
+func sign(v float64) float64 {
+	switch {
+	case v > 0:
+		return 1
+	case v < 0:
+		return -1
+	}
+	return 0
+}
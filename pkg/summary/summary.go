@@ -0,0 +1,40 @@
+// Package summary describes the metric history of a test stored in PerfRepo.
+package summary
+
+import (
+	"github.com/pkg/errors"
+
+	"github.com/mgencur/go-perfrepoclient/pkg/apis"
+	"github.com/mgencur/go-perfrepoclient/pkg/client"
+	"github.com/mgencur/go-perfrepoclient/pkg/stats"
+)
+
+// Test searches executions of the test matching the criteria and summarizes values of each metric
+// of the test, see stats.Summarize. The criteria are restricted to the given test.
+func Test(c *client.PerfRepoClient, testID int64, criteria *apis.TestExecutionSearch,
+	groupBy string) ([]stats.Summary, error) {
+	test, err := c.GetTest(testID)
+	if err != nil {
+		return nil, errors.Wrap(err, "Failed to summarize metric history")
+	}
+	search := apis.TestExecutionSearch{}
+	if criteria != nil {
+		search = *criteria
+	}
+	search.TestUID = test.UID
+	executions, err := c.SearchTestExecutions(&search)
+	if err != nil {
+		return nil, errors.Wrap(err, "Failed to summarize metric history")
+	}
+	metrics := make(map[string]bool)
+	for _, m := range test.Metrics {
+		metrics[m.Name] = true
+	}
+	var summaries []stats.Summary
+	for _, s := range stats.Summarize(executions, groupBy) {
+		if metrics[s.Metric] {
+			summaries = append(summaries, s)
+		}
+	}
+	return summaries, nil
+}
@@ -0,0 +1,52 @@
+package summary
+
+import (
+	"encoding/xml"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/mgencur/go-perfrepoclient/pkg/apis"
+	"github.com/mgencur/go-perfrepoclient/pkg/client"
+)
+
+const testXML = `<test id="1" name="service" groupId="perfrepouser" uid="service">
+<metrics><metric name="latency" comparator="LB"/></metrics></test>`
+
+const executionsXML = `<testExecutions>
+<testExecution id="1" name="e1" testId="1" testUid="service" started="2016-07-01T00:00:00-00:00">
+<values><value metricName="latency" result="100"/><value metricName="removed" result="1"/></values></testExecution>
+<testExecution id="2" name="e2" testId="1" testUid="service" started="2016-07-02T00:00:00-00:00">
+<values><value metricName="latency" result="120"/></values></testExecution>
+</testExecutions>`
+
+func TestTest(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/rest/test/id/1":
+			w.Write([]byte(testXML))
+		case "/rest/testExecution/search":
+			body, _ := ioutil.ReadAll(r.Body)
+			var criteria apis.TestExecutionSearch
+			if err := xml.Unmarshal(body, &criteria); err != nil || criteria.TestUID != "service" || criteria.Tags != "nightly" {
+				w.WriteHeader(http.StatusBadRequest)
+				return
+			}
+			w.Write([]byte(executionsXML))
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer server.Close()
+
+	summaries, err := Test(client.NewClient(server.URL, "user", "pass"), 1,
+		&apis.TestExecutionSearch{TestUID: "other", Tags: "nightly"}, "")
+	if err != nil {
+		t.Fatal("Failed to summarize test", err)
+	}
+	//metrics no longer defined by the test are left out
+	if len(summaries) != 1 || summaries[0].Metric != "latency" || summaries[0].Count != 2 || summaries[0].Mean != 110 {
+		t.Errorf("Unexpected summaries %+v", summaries)
+	}
+}
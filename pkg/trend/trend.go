@@ -0,0 +1,171 @@
+// Package trend detects slow drifts of metrics over time which slip past per-run thresholds.
+package trend
+
+import (
+	"sort"
+	"time"
+
+	"github.com/pkg/errors"
+
+	"github.com/mgencur/go-perfrepoclient/pkg/apis"
+	"github.com/mgencur/go-perfrepoclient/pkg/client"
+	"github.com/mgencur/go-perfrepoclient/pkg/compare"
+	"github.com/mgencur/go-perfrepoclient/pkg/stats"
+)
+
+const (
+	defaultAlpha      = 0.05
+	defaultConfidence = 0.95
+	defaultMinSamples = 5
+
+	day  = 24 * time.Hour
+	week = 7
+)
+
+// Options configures the analysis. Zero values select defaults.
+type Options struct {
+	Alpha      float64 // significance level of the Mann-Kendall test, 0.05 by default
+	Confidence float64 // confidence of the slope interval, 0.95 by default
+	MinSamples int     // histories with fewer values are skipped, 5 by default
+	// MinWeeklyChange is the minimal relative change per week to report, e.g. 0.005 for 0.5%
+	MinWeeklyChange float64
+	// Comparator of the metric, the comparator of the values is used when unknown
+	Comparator apis.Comparator
+}
+
+// Trend describes the drift of a metric sharing the same value parameters
+type Trend struct {
+	Metric          string
+	Parameters      string // value parameters, see apis.Value.ParametersKey
+	Comparator      apis.Comparator
+	Count           int
+	From            time.Time // start of the first execution
+	To              time.Time // start of the last execution
+	Median          float64
+	SlopePerDay     float64
+	SlopePerWeek    float64
+	WeeklyChange    float64        // SlopePerWeek relative to the median
+	Interval        stats.Interval // confidence interval of SlopePerDay
+	IntervalPerWeek stats.Interval // confidence interval of SlopePerWeek
+	Intercept       float64        // fitted value at From
+	MannKendall     stats.TestResult
+	Significant     bool // the trend test is significant and the interval doesn't contain zero
+	Verdict         compare.Verdict
+	Insufficient    bool // too few samples, the verdict is Unchanged
+}
+
+// AnalyzeSearch searches executions matching the criteria and analyzes trends of the metric
+func AnalyzeSearch(c *client.PerfRepoClient, criteria *apis.TestExecutionSearch, metric string,
+	opts Options) ([]Trend, error) {
+	executions, err := c.SearchTestExecutions(criteria)
+	if err != nil {
+		return nil, errors.Wrap(err, "Failed to fetch metric history")
+	}
+	return Analyze(executions, metric, opts), nil
+}
+
+// Analyze fits a Theil-Sen line of the metric values against the start of the executions.
+// Multi-value metrics yield a trend for each combination of value parameters. A significant
+// slope is reported as a regression or an improvement according to the metric comparator when
+// the weekly change is at least MinWeeklyChange. Executions without start time are ignored.
+func Analyze(executions []apis.TestExecution, metric string, opts Options) []Trend {
+	opts.setDefaults()
+	type history struct {
+		comparator apis.Comparator
+		started    []time.Time
+		results    []float64
+	}
+	histories := make(map[string]*history)
+	for i := range executions {
+		if executions[i].Started == nil {
+			continue
+		}
+		for j := range executions[i].Values {
+			v := &executions[i].Values[j]
+			if v.MetricName != metric {
+				continue
+			}
+			key := v.ParametersKey()
+			h, ok := histories[key]
+			if !ok {
+				h = &history{}
+				histories[key] = h
+			}
+			if h.comparator == apis.UnknownComparator {
+				h.comparator = v.MetricComparator
+			}
+			h.started = append(h.started, executions[i].Started.Time)
+			h.results = append(h.results, v.Result)
+		}
+	}
+
+	var trends []Trend
+	for key, h := range histories {
+		comparator := opts.Comparator
+		if comparator == apis.UnknownComparator {
+			comparator = h.comparator
+		}
+		trends = append(trends, fit(metric, key, comparator, h.started, h.results, opts))
+	}
+	sort.Slice(trends, func(i, j int) bool {
+		return trends[i].Parameters < trends[j].Parameters
+	})
+	return trends
+}
+
+func fit(metric, params string, comparator apis.Comparator, started []time.Time, results []float64,
+	opts Options) Trend {
+	t := Trend{
+		Metric:     metric,
+		Parameters: params,
+		Comparator: comparator,
+		Count:      len(results),
+		Median:     stats.Median(results),
+		Verdict:    compare.Unchanged,
+	}
+	t.From, t.To = started[0], started[0]
+	for _, s := range started {
+		if s.Before(t.From) {
+			t.From = s
+		}
+		if s.After(t.To) {
+			t.To = s
+		}
+	}
+	if len(results) < opts.MinSamples || !t.To.After(t.From) {
+		t.Insufficient = true
+		return t
+	}
+
+	days := make([]float64, len(started))
+	for i, s := range started {
+		days[i] = float64(s.Sub(t.From)) / float64(day)
+	}
+	line, interval := stats.TheilSen(days, results, opts.Confidence)
+	t.SlopePerDay = line.Slope
+	t.SlopePerWeek = line.Slope * week
+	t.Intercept = line.Intercept
+	t.Interval = interval
+	t.IntervalPerWeek = stats.Interval{Lower: interval.Lower * week, Upper: interval.Upper * week}
+	t.WeeklyChange = compare.RelativeChange(t.Median, t.Median+t.SlopePerWeek)
+	t.MannKendall = stats.MannKendall(days, results)
+
+	t.Significant = t.MannKendall.PValue < opts.Alpha && !interval.Contains(0)
+	if t.Significant {
+		t.Verdict = compare.Judge(comparator, t.SlopePerWeek, t.WeeklyChange,
+			compare.Threshold{Relative: opts.MinWeeklyChange})
+	}
+	return t
+}
+
+func (o *Options) setDefaults() {
+	if o.Alpha <= 0 {
+		o.Alpha = defaultAlpha
+	}
+	if o.Confidence <= 0 {
+		o.Confidence = defaultConfidence
+	}
+	if o.MinSamples <= 0 {
+		o.MinSamples = defaultMinSamples
+	}
+}
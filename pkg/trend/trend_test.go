@@ -0,0 +1,74 @@
+package trend
+
+import (
+	"math"
+	"testing"
+	"time"
+
+	"github.com/mgencur/go-perfrepoclient/pkg/apis"
+	"github.com/mgencur/go-perfrepoclient/pkg/compare"
+)
+
+// daily creates an execution a day with value 100 + slope * day and alternating noise
+func daily(count int, slope, noise float64, comparator apis.Comparator) []apis.TestExecution {
+	start := time.Date(2026, time.January, 1, 0, 0, 0, 0, time.UTC)
+	execs := make([]apis.TestExecution, count)
+	for i := range execs {
+		result := 100 + slope*float64(i) + noise*float64(1-2*(i%2))
+		execs[i] = apis.TestExecution{
+			Started: &apis.JaxbTime{Time: start.Add(time.Duration(i) * day)},
+			Values:  []apis.Value{{MetricName: "m", MetricComparator: comparator, Result: result}},
+		}
+	}
+	return execs
+}
+
+func TestAnalyze(t *testing.T) {
+	cases := []struct {
+		name         string
+		executions   []apis.TestExecution
+		opts         Options
+		slopePerWeek float64
+		significant  bool
+		verdict      compare.Verdict
+		insufficient bool
+	}{
+		{"LB rising", daily(14, 1, 0.5, apis.LBComparator), Options{}, 7, true, compare.Regressed, false},
+		{"HB rising", daily(14, 1, 0.5, apis.HBComparator), Options{}, 7, true, compare.Improved, false},
+		{"HB falling", daily(14, -1, 0.5, apis.HBComparator), Options{}, -7, true, compare.Regressed, false},
+		{"comparator option", daily(14, 1, 0.5, apis.UnknownComparator), Options{Comparator: apis.LBComparator},
+			7, true, compare.Regressed, false},
+		{"flat", daily(14, 0, 0.5, apis.LBComparator), Options{}, 0, false, compare.Unchanged, false},
+		{"below MinWeeklyChange", daily(14, 1, 0.5, apis.LBComparator), Options{MinWeeklyChange: 0.5},
+			7, true, compare.Unchanged, false},
+		{"too few samples", daily(4, 1, 0, apis.LBComparator), Options{}, 0, false, compare.Unchanged, true},
+	}
+	for _, c := range cases {
+		trends := Analyze(c.executions, "m", c.opts)
+		if len(trends) != 1 {
+			t.Errorf("%s: expected 1 trend, got %+v", c.name, trends)
+			continue
+		}
+		tr := trends[0]
+		if math.Abs(tr.SlopePerWeek-c.slopePerWeek) > 0.5 || tr.Significant != c.significant ||
+			tr.Verdict != c.verdict || tr.Insufficient != c.insufficient {
+			t.Errorf("%s: unexpected trend %+v", c.name, tr)
+		}
+		if c.significant && (tr.IntervalPerWeek.Lower > tr.SlopePerWeek || tr.IntervalPerWeek.Upper < tr.SlopePerWeek) {
+			t.Errorf("%s: slope %v outside its interval %+v", c.name, tr.SlopePerWeek, tr.IntervalPerWeek)
+		}
+	}
+}
+
+func TestAnalyzeIgnoresExecutionsWithoutStart(t *testing.T) {
+	executions := daily(3, 1, 0, apis.LBComparator)
+	for i := range executions {
+		executions[i].Started.Time = executions[0].Started.Time
+	}
+	executions = append(executions, apis.TestExecution{Values: []apis.Value{{MetricName: "m", Result: 1000}}})
+	trends := Analyze(executions, "m", Options{MinSamples: 2})
+	//executions started at the same time can't show a trend
+	if len(trends) != 1 || trends[0].Count != 3 || !trends[0].Insufficient {
+		t.Errorf("Unexpected trends %+v", trends)
+	}
+}
@@ -3,15 +3,21 @@
 package e2e
 
 import (
+	"bytes"
+	"context"
 	"encoding/xml"
+	"errors"
 	"io/ioutil"
 	"os"
+	"strconv"
 	"strings"
 	"testing"
 	"time"
 
 	"github.com/mgencur/go-perfrepoclient/pkg/apis"
 	"github.com/mgencur/go-perfrepoclient/pkg/client"
+	"github.com/mgencur/go-perfrepoclient/pkg/outlier"
+	"github.com/mgencur/go-perfrepoclient/pkg/render"
 	"github.com/mgencur/go-perfrepoclient/test"
 )
 
@@ -407,6 +413,234 @@ func TestSearchTestExecutions(t *testing.T) {
 		t.Fatalf("The returned test executions do not match the search criteria. Executions: %+v, Criteria: %+v",
 			executions, criteria)
 	}
+
+	// create 4. search
+	ids = []int64{testExec2ID}
+	criteria, err = apis.NewSearch().
+		ForTestUID(test1In.UID).
+		WithTags("tag2").
+		WithoutTags("tag1").
+		ExecutedBetween(time.Date(2016, time.July, 1, 0, 0, 0, 0, time.UTC),
+			time.Date(2016, time.July, 31, 0, 0, 0, 0, time.UTC)).
+		OrderByParameter("param1", true).
+		Page(0, 10).
+		Build()
+	if err != nil {
+		t.Fatal("Failed to build search criteria", err.Error())
+	}
+	executions, err = testClient.SearchTestExecutions(criteria)
+
+	if len(ids) != len(executions) ||
+		!idsIncluded(executions, ids...) {
+		t.Fatalf("The returned test executions do not match the search criteria. Executions: %+v, Criteria: %+v",
+			executions, criteria)
+	}
+
+	if _, err = apis.NewSearch().OrderBy(apis.ParameterAscOrderBy).Build(); err == nil {
+		t.Fatal("Ordering by parameter without parameter name accepted")
+	}
+
+	// create 5. search
+	tagExpr, err := apis.ParseTagExpr("tag1 OR (tag4 AND NOT tag2)")
+	if err != nil {
+		t.Fatal("Failed to parse tag expression", err.Error())
+	}
+	//restrict the searches to the created tests, the server may hold other executions with these tags
+	for testUID, ids := range map[string][]int64{test1In.UID: {testExec1ID}, test2In.UID: {testExec3ID}} {
+		executions, err = testClient.SearchTestExecutionsByTags(&apis.TestExecutionSearch{TestUID: testUID}, tagExpr)
+		if err != nil {
+			t.Fatal("Failed to search TestExecutions by tags", err.Error())
+		}
+
+		if len(ids) != len(executions) ||
+			!idsIncluded(executions, ids...) {
+			t.Fatalf("The returned test executions do not match the tag expression. Executions: %+v, Expression: %v",
+				executions, tagExpr)
+		}
+	}
+}
+
+func TestIterateTestExecutions(t *testing.T) {
+	testIn := test.Test("test1")
+	testID, err := testClient.CreateTest(testIn)
+	if err != nil {
+		t.Fatal("Failed to create Test", err.Error())
+	}
+	defer func() {
+		if err := testClient.DeleteTest(testID); err != nil {
+			t.Fatal(err.Error())
+		}
+	}()
+
+	var ids []int64
+	started := time.Now().Add(-time.Hour)
+	for i := 0; i < 5; i++ {
+		//distinct start times make the order of the executions deterministic
+		exec := test.Execution(testID, &apis.JaxbTime{Time: started.Add(time.Duration(i) * time.Minute)}, nil, nil)
+		testExecID, err := testClient.CreateTestExecution(exec)
+		if err != nil {
+			t.Fatal("Failed to create TestExecution", err.Error())
+		}
+		defer func() {
+			if err := testClient.DeleteTestExecution(testExecID); err != nil {
+				t.Fatal(err.Error())
+			}
+		}()
+		ids = append(ids, testExecID)
+	}
+
+	criteria := &apis.TestExecutionSearch{TestUID: testIn.UID}
+
+	var executions []apis.TestExecution
+	it := testClient.IterateTestExecutions(context.Background(), criteria, 2, 0)
+	for it.Next() {
+		executions = append(executions, *it.Execution())
+	}
+	if it.Err() != nil {
+		t.Fatal("Failed to iterate TestExecutions", it.Err().Error())
+	}
+	if len(executions) != len(ids) {
+		t.Fatalf("The iterated test executions do not match the created ones. Executions: %+v, IDs: %v",
+			executions, ids)
+	}
+	for i := range executions {
+		if executions[i].ID != ids[i] {
+			t.Fatalf("Test executions not iterated by date. Executions: %+v, IDs: %v", executions, ids)
+		}
+	}
+
+	//HowMany of the criteria limits the results like the limit argument
+	count := 0
+	it = testClient.IterateTestExecutions(context.Background(),
+		&apis.TestExecutionSearch{TestUID: testIn.UID, HowMany: 4}, 2, 0)
+	for it.Next() {
+		count++
+	}
+	if it.Err() != nil || count != 4 {
+		t.Fatalf("HowMany not respected, got %d executions, error: %v", count, it.Err())
+	}
+
+	count = 0
+	it = testClient.IterateTestExecutions(context.Background(), criteria, 2, 3)
+	for it.Next() {
+		count++
+	}
+	if it.Err() != nil || count != 3 {
+		t.Fatalf("Limit not respected, got %d executions, error: %v", count, it.Err())
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	it = testClient.IterateTestExecutions(ctx, criteria, 2, 0)
+	if it.Next() || it.Err() == nil {
+		t.Fatal("Iteration with cancelled context succeeded")
+	}
+}
+
+func TestStreamTestExecutions(t *testing.T) {
+	testIn := test.Test("test1")
+	testID, err := testClient.CreateTest(testIn)
+	if err != nil {
+		t.Fatal("Failed to create Test", err.Error())
+	}
+	defer func() {
+		if err := testClient.DeleteTest(testID); err != nil {
+			t.Fatal(err.Error())
+		}
+	}()
+
+	var ids []int64
+	for i := 0; i < 3; i++ {
+		testExecID, err := testClient.CreateTestExecution(test.DefaultExecution(testID))
+		if err != nil {
+			t.Fatal("Failed to create TestExecution", err.Error())
+		}
+		defer func() {
+			if err := testClient.DeleteTestExecution(testExecID); err != nil {
+				t.Fatal(err.Error())
+			}
+		}()
+		ids = append(ids, testExecID)
+	}
+
+	var executions []apis.TestExecution
+	err = testClient.StreamTestExecutions(context.Background(), &apis.TestExecutionSearch{TestUID: testIn.UID},
+		func(exec *apis.TestExecution) error {
+			executions = append(executions, *exec)
+			return nil
+		})
+	if err != nil {
+		t.Fatal("Failed to stream TestExecutions", err.Error())
+	}
+	if len(executions) != len(ids) || !idsIncluded(executions, ids...) ||
+		firstMetricByParam(&executions[0], "multimetric",
+			apis.ValueParameter{Name: "client", Value: "2"}) != 40.0 {
+		t.Fatalf("The streamed test executions do not match the created ones. Executions: %+v, IDs: %v",
+			executions, ids)
+	}
+
+	stop := errors.New("stop")
+	count := 0
+	err = testClient.StreamTestExecutions(context.Background(), &apis.TestExecutionSearch{TestUID: testIn.UID},
+		func(exec *apis.TestExecution) error {
+			count++
+			return stop
+		})
+	if err != stop || count != 1 {
+		t.Fatalf("Streaming not stopped by callback error, got %d executions, error: %v", count, err)
+	}
+}
+
+func TestGetTestExecutions(t *testing.T) {
+	testIn := test.Test("test1")
+	testID, err := testClient.CreateTest(testIn)
+	if err != nil {
+		t.Fatal("Failed to create Test", err.Error())
+	}
+	defer func() {
+		if err := testClient.DeleteTest(testID); err != nil {
+			t.Fatal(err.Error())
+		}
+	}()
+
+	var ids []int64
+	for i := 0; i < 4; i++ {
+		testExecID, err := testClient.CreateTestExecution(test.DefaultExecution(testID))
+		if err != nil {
+			t.Fatal("Failed to create TestExecution", err.Error())
+		}
+		defer func() {
+			if err := testClient.DeleteTestExecution(testExecID); err != nil {
+				t.Fatal(err.Error())
+			}
+		}()
+		ids = append(ids, testExecID)
+	}
+
+	missingID := ids[len(ids)-1] + 100000
+	requested := []int64{ids[2], ids[0], missingID, ids[2], ids[1], ids[3]}
+	results, err := testClient.GetTestExecutions(context.Background(), requested, 2)
+
+	bulkErr, ok := err.(*client.BulkFetchError)
+	if !ok || len(bulkErr.NotFound) != 1 || bulkErr.NotFound[0] != missingID || len(bulkErr.Failed) != 0 {
+		t.Fatalf("Missing test execution not reported: %v", err)
+	}
+	expected := []int64{ids[2], ids[0], missingID, ids[1], ids[3]}
+	if len(results) != len(expected) {
+		t.Fatalf("Expected %d results, got %d", len(expected), len(results))
+	}
+	for i, r := range results {
+		if r.ID != expected[i] {
+			t.Fatalf("Results not in input order: %+v", results)
+		}
+		if r.ID == missingID {
+			if r.Err == nil || !client.IsNotFound(r.Err) || r.Execution != nil {
+				t.Fatalf("Unexpected result for missing execution: %+v", r)
+			}
+		} else if r.Err != nil || r.Execution == nil || r.Execution.ID != r.ID {
+			t.Fatalf("Unexpected result for execution %d: %+v", r.ID, r)
+		}
+	}
 }
 
 func TestCreateGetAttachment(t *testing.T) {
@@ -596,6 +830,251 @@ func TestCreateDeleteReportPermission(t *testing.T) {
 	}
 }
 
+func TestReconcileReportPermissions(t *testing.T) {
+	report := test.Report("report", test.Flags.User)
+
+	reportID, err := testClient.CreateReport(report)
+	if err != nil {
+		t.Fatal("Failed to create Report", err.Error())
+	}
+	defer func() {
+		if err := testClient.DeleteReport(reportID); err != nil {
+			t.Fatal(err.Error())
+		}
+	}()
+
+	reportOut, err := testClient.GetReport(reportID)
+	if err != nil {
+		t.Fatal("Failed to get Report", err.Error())
+	}
+	defaultPermission := reportOut.Permissions[0]
+	publicPermission := apis.Permission{
+		AccessLevel: apis.PublicAccessLevel,
+		AccessType:  apis.ReadAccessType,
+	}
+	desired := []apis.Permission{defaultPermission, publicPermission}
+
+	changes, err := testClient.ReconcileReportPermissions(reportID, desired, true)
+	if err != nil {
+		t.Fatal("Failed to reconcile permissions", err.Error())
+	}
+	reportOut, err = testClient.GetReport(reportID)
+	if err != nil {
+		t.Fatal("Failed to get Report", err.Error())
+	}
+	if len(changes.Added) != 1 || len(changes.Removed) != 0 ||
+		len(reportOut.Permissions) != 1 {
+		t.Fatalf("Unexpected dry-run changes: %+v", changes)
+	}
+
+	changes, err = testClient.ReconcileReportPermissions(reportID, desired, false)
+	if err != nil {
+		t.Fatal("Failed to reconcile permissions", err.Error())
+	}
+	reportOut, err = testClient.GetReport(reportID)
+	if err != nil {
+		t.Fatal("Failed to get Report", err.Error())
+	}
+	if len(changes.Added) != 1 || changes.Added[0].ID == 0 ||
+		len(reportOut.Permissions) != 2 ||
+		!containsPermission(reportOut.Permissions, &publicPermission) {
+		t.Fatalf("Permissions not reconciled: %+v", reportOut.Permissions)
+	}
+
+	changes, err = testClient.ReconcileReportPermissions(reportID, []apis.Permission{defaultPermission}, false)
+	if err != nil {
+		t.Fatal("Failed to reconcile permissions", err.Error())
+	}
+	reportOut, err = testClient.GetReport(reportID)
+	if err != nil {
+		t.Fatal("Failed to get Report", err.Error())
+	}
+	if len(changes.Added) != 0 || len(changes.Removed) != 1 ||
+		len(reportOut.Permissions) != 1 ||
+		containsPermission(reportOut.Permissions, &publicPermission) {
+		t.Fatalf("Permissions not reconciled: %+v", reportOut.Permissions)
+	}
+}
+
+func TestCreateReportFromTemplate(t *testing.T) {
+	test1In := test.Test("test1")
+	test1ID, err := testClient.CreateTest(test1In)
+	if err != nil {
+		t.Fatal("Failed to create Test", err.Error())
+	}
+	defer func() {
+		if err := testClient.DeleteTest(test1ID); err != nil {
+			t.Fatal(err.Error())
+		}
+	}()
+
+	test2In := test.Test("test2")
+	test2ID, err := testClient.CreateTest(test2In)
+	if err != nil {
+		t.Fatal("Failed to create Test", err.Error())
+	}
+	defer func() {
+		if err := testClient.DeleteTest(test2ID); err != nil {
+			t.Fatal(err.Error())
+		}
+	}()
+
+	report := test.Report("report", test.Flags.User)
+	report.Properties["chart1.test"] = strconv.FormatInt(test1ID, 10)
+	report.Properties["chart1.metric"] = "metric1"
+
+	template, err := client.NewReportTemplate(report,
+		client.TemplateVariable{Property: "chart1.test", Kind: client.TestVariable},
+		client.TemplateVariable{Property: "chart1.metric", Kind: client.MetricVariable, TestProperty: "chart1.test"})
+	if err != nil {
+		t.Fatal("Failed to create report template", err.Error())
+	}
+
+	_, err = testClient.InstantiateReportTemplate(template, "invalid", map[string]string{
+		"chart1.test":   test2In.UID,
+		"chart1.metric": "nonexistent",
+	})
+	if err == nil {
+		t.Fatal("Report with nonexistent metric instantiated")
+	}
+
+	reportID, err := testClient.CreateReportFromTemplate(template, report.Name+"-test2", map[string]string{
+		"chart1.test":   test2In.UID,
+		"chart1.metric": "metric2",
+	})
+	if err != nil {
+		t.Fatal("Failed to create Report from template", err.Error())
+	}
+	defer func() {
+		if err := testClient.DeleteReport(reportID); err != nil {
+			t.Fatal(err.Error())
+		}
+	}()
+
+	reportOut, err := testClient.GetReport(reportID)
+	if err != nil {
+		t.Fatal("Failed to get Report", err.Error())
+	}
+
+	if reportOut.Name != report.Name+"-test2" ||
+		reportOut.Properties["chart1.test"] != strconv.FormatInt(test2ID, 10) ||
+		reportOut.Properties["chart1.metric"] != "metric2" ||
+		reportOut.Properties["property1"] != report.Properties["property1"] {
+		t.Fatalf("The returned report: %+v does not match the template %+v", reportOut, report)
+	}
+}
+
+func TestRenderMetricHistory(t *testing.T) {
+	testIn := test.Test("test1")
+
+	testID, err := testClient.CreateTest(testIn)
+	if err != nil {
+		t.Fatal("Failed to create Test", err.Error())
+	}
+	defer func() {
+		if err := testClient.DeleteTest(testID); err != nil {
+			t.Fatal(err.Error())
+		}
+	}()
+
+	testExecID, err := testClient.CreateTestExecution(test.DefaultExecution(testID))
+	if err != nil {
+		t.Fatal("Failed to create TestExecution", err.Error())
+	}
+	defer func() {
+		if err := testClient.DeleteTestExecution(testExecID); err != nil {
+			t.Fatal(err.Error())
+		}
+	}()
+
+	var page bytes.Buffer
+	err = render.MetricHistory(testClient, &page, testID, []string{"metric1", "multimetric"},
+		&apis.TestExecutionSearch{}, render.Options{KeyParameters: []string{"param1"}})
+	if err != nil {
+		t.Fatal("Failed to render metric history", err.Error())
+	}
+
+	html := page.String()
+	if !strings.Contains(html, "<svg") ||
+		!strings.Contains(html, "metric1") ||
+		!strings.Contains(html, "client=2") ||
+		!strings.Contains(html, "param1=value1") ||
+		strings.Contains(html, "metric2") {
+		t.Fatalf("The rendered page doesn't contain expected charts: %s", html)
+	}
+}
+
+func TestFlagOutliers(t *testing.T) {
+	testIn := test.Test("test1")
+
+	testID, err := testClient.CreateTest(testIn)
+	if err != nil {
+		t.Fatal("Failed to create Test", err.Error())
+	}
+	defer func() {
+		if err := testClient.DeleteTest(testID); err != nil {
+			t.Fatal(err.Error())
+		}
+	}()
+
+	var outlierID int64
+	for i := 0; i < 6; i++ {
+		testExec := test.DefaultExecution(testID)
+		if i == 0 {
+			testExec.Values[0].Result = 100.0
+		}
+		testExecID, err := testClient.CreateTestExecution(testExec)
+		if err != nil {
+			t.Fatal("Failed to create TestExecution", err.Error())
+		}
+		defer func() {
+			if err := testClient.DeleteTestExecution(testExecID); err != nil {
+				t.Fatal(err.Error())
+			}
+		}()
+		if i == 0 {
+			outlierID = testExecID
+		}
+	}
+
+	criteria := &apis.TestExecutionSearch{TestUID: testIn.UID}
+	opts := outlier.Options{Metrics: []string{"metric1"}}
+
+	result, err := outlier.Flag(testClient, criteria, opts, true)
+	if err != nil {
+		t.Fatal("Failed to find outliers", err.Error())
+	}
+	if len(result.Outliers) != 1 || len(result.Untagged) != 1 || result.Untagged[0] != outlierID ||
+		len(result.Tagged) != 0 {
+		t.Fatalf("Unexpected outliers: %+v", result)
+	}
+	testExecOut, err := testClient.GetTestExecution(outlierID)
+	if err != nil {
+		t.Fatal("Failed to get TestExecution", err.Error())
+	}
+	for _, tag := range testExecOut.Tags {
+		if tag.Name == outlier.DefaultTag {
+			t.Fatal("The outlier was tagged in dry run")
+		}
+	}
+
+	result, err = outlier.Flag(testClient, criteria, opts, false)
+	if err != nil {
+		t.Fatal("Failed to flag outliers", err.Error())
+	}
+	if len(result.Tagged) != 1 || result.Tagged[0] != outlierID {
+		t.Fatalf("Unexpected tagged executions: %+v", result)
+	}
+	criteria.Tags = outlier.DefaultTag
+	flagged, err := testClient.SearchTestExecutions(criteria)
+	if err != nil {
+		t.Fatal("Failed to search TestExecutions", err.Error())
+	}
+	if len(flagged) != 1 || !idsIncluded(flagged, outlierID) {
+		t.Fatalf("Expected only the outlier to be tagged, got %+v", flagged)
+	}
+}
+
 func paramsEqual(actual, expected *apis.TestExecution) bool {
 	actualSorted := actual.SortedParameters()
 	for i, p := range expected.SortedParameters() {
@@ -679,16 +1158,8 @@ func valueParamsEqual(expected, actual apis.Value) bool {
 }
 
 func firstMetricByParam(testExec *apis.TestExecution, metricName string, param apis.ValueParameter) float64 {
-	for _, v := range testExec.Values {
-		if v.MetricName == metricName {
-			for _, p := range v.Parameters {
-				if p == param {
-					return v.Result
-				}
-			}
-		}
-	}
-	return 0.0
+	result, _ := testExec.Series(metricName, param.Name).Result(param.Value)
+	return result
 }
 
 func propertiesEqual(actual, expected *apis.Report, propsToCompare ...string) bool {